@@ -0,0 +1,109 @@
+package rdf
+
+import "sort"
+
+// Connectivity defines when two triples are connected, and so belong to the
+// same component, for Graph.ConnectedComponents.
+type Connectivity int
+
+const (
+	// SharedSubjectOrBlank connects triples with the same subject, or which
+	// share a blank node, in any position. Each component is then a top-level
+	// entity, together with the blank nodes describing it.
+	SharedSubjectOrBlank Connectivity = iota
+
+	// SharedNode connects triples sharing a node, i.e. an IRI, blank node or
+	// quoted triple in subject or object position. Literals and predicates
+	// never connect triples.
+	SharedNode
+)
+
+// ConnectedComponents splits the graph into its connected components, where
+// the triples are connected as defined by the given Connectivity. Every triple
+// of the graph is in exactly one of the returned graphs, which share no
+// blank nodes, and can therefore be processed independently.
+//
+// The components are returned in a deterministic order, by the smallest
+// N-Triples serialization of their triples.
+func (g *Graph) ConnectedComponents(c Connectivity) []*Graph {
+	keys := make([]string, 0, len(g.triples))
+	for k := range g.triples {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	// Union-find over the triples, by index in keys.
+	parent := make([]int, len(keys))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	seen := make(map[string]int) // node -> first triple having it
+	connect := func(i int, node Term) {
+		k := node.Serialize(NTriples)
+		j, ok := seen[k]
+		if !ok {
+			seen[k] = i
+			return
+		}
+		if ri, rj := find(i), find(j); ri != rj {
+			if ri < rj {
+				parent[rj] = ri
+			} else {
+				parent[ri] = rj
+			}
+		}
+	}
+
+	for i, k := range keys {
+		t := g.triples[k]
+		switch c {
+		case SharedSubjectOrBlank:
+			connect(i, t.Subj)
+		case SharedNode:
+			connect(i, t.Subj)
+			if t.Obj.Type() != TermLiteral {
+				connect(i, t.Obj)
+			}
+		}
+		for _, b := range blanksOf(t) {
+			connect(i, b)
+		}
+	}
+
+	var comps []*Graph
+	index := make(map[int]int) // root -> index in comps
+	for i, k := range keys {
+		r := find(i)
+		n, ok := index[r]
+		if !ok {
+			n = len(comps)
+			index[r] = n
+			comps = append(comps, NewGraph())
+		}
+		comps[n].Add(g.triples[k])
+	}
+	return comps
+}
+
+// blanksOf returns the blank nodes of the triple, including those
+// inside quoted triples.
+func blanksOf(t Triple) []Blank {
+	var bs []Blank
+	for _, term := range [2]Term{t.Subj, t.Obj} {
+		switch term := term.(type) {
+		case Blank:
+			bs = append(bs, term)
+		case QuotedTriple:
+			bs = append(bs, blanksOf(term.Triple)...)
+		}
+	}
+	return bs
+}
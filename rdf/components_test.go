@@ -0,0 +1,48 @@
+package rdf
+
+import "testing"
+
+func TestConnectedComponents(t *testing.T) {
+	g := NewGraph()
+	g.Add(mustParseTriples(t, `
+@prefix ex: <http://example.org/> .
+ex:a ex:name "A" ; ex:address [ ex:city "Oslo" ; ex:geo [ ex:lat 1 ] ] .
+ex:a ex:knows ex:b .
+ex:b ex:name "B" .
+ex:c ex:name "A" .
+<< ex:d ex:p _:x >> ex:source ex:e .
+_:x ex:q ex:r .`)...)
+
+	tests := []struct {
+		c    Connectivity
+		want []int // sizes of components, in order
+	}{
+		// quoted triple with _:x | ex:a with its blank nodes | ex:b | ex:c
+		{SharedSubjectOrBlank, []int{2, 6, 1, 1}},
+		// quoted triple with _:x | ex:a, its blank nodes and ex:b | ex:c
+		{SharedNode, []int{2, 7, 1}},
+	}
+
+	for _, tt := range tests {
+		comps := g.ConnectedComponents(tt.c)
+		var sizes []int
+		total := 0
+		for _, comp := range comps {
+			sizes = append(sizes, comp.Len())
+			total += comp.Len()
+		}
+		if total != g.Len() {
+			t.Errorf("ConnectedComponents(%v): components hold %d triples; want %d", tt.c, total, g.Len())
+		}
+		if len(sizes) != len(tt.want) {
+			t.Errorf("ConnectedComponents(%v) => component sizes %v; want %v", tt.c, sizes, tt.want)
+			continue
+		}
+		for i := range sizes {
+			if sizes[i] != tt.want[i] {
+				t.Errorf("ConnectedComponents(%v) => component sizes %v; want %v", tt.c, sizes, tt.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package rdf
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Dataset is an in-memory RDF dataset: a default graph, plus any number of
+// named graphs. The graph name can be an IRI or a blank node.
+//
+// Quads are assigned to graphs by their context. Quads with no context, or
+// with the context of the DefaultGraph, are added to the default graph.
+type Dataset struct {
+	// DefaultGraph is the context denoting the default graph. It defaults to
+	// the same context as the QuadDecoder uses, so decoded quads land in
+	// the right graph.
+	DefaultGraph Context
+
+	def    *Graph
+	named  map[string]*Graph  // named graphs, keyed by the N-Triples serialization of the name
+	names  map[string]Context // graph names, with the same keys
+	blanks map[string]bool    // identifiers of the blank nodes in the dataset
+	nBlank int                // counter to generate unique blank node identifiers
+}
+
+// NewDataset returns a new, empty Dataset.
+func NewDataset() *Dataset {
+	return &Dataset{
+		DefaultGraph: defaultGraph,
+		def:          NewGraph(),
+		named:        make(map[string]*Graph),
+		names:        make(map[string]Context),
+		blanks:       make(map[string]bool),
+	}
+}
+
+// Add adds the given quads to the dataset, creating any named graphs
+// not already in it.
+func (ds *Dataset) Add(qs ...Quad) {
+	for _, q := range qs {
+		for _, b := range blanksOf(q.Triple) {
+			ds.blanks[b.id] = true
+		}
+		ds.AddGraph(q.Ctx).Add(q.Triple)
+	}
+}
+
+// AddGraph returns the graph with the given name, adding it to the dataset as
+// an empty named graph if it is not already in it. A nil name, or the
+// DefaultGraph, returns the default graph.
+//
+// An empty named graph is one which is asserted to exist, but has no triples,
+// like the TriG graph block <g> {}. It is listed by Names, but contributes no
+// quads to Len, ToQuads or Diff.
+func (ds *Dataset) AddGraph(name Context) *Graph {
+	if (Quad{Ctx: name}).InDefaultGraph(ds.DefaultGraph) {
+		return ds.def
+	}
+	if b, ok := name.(Blank); ok {
+		ds.blanks[b.id] = true
+	}
+	k := name.Serialize(NTriples)
+	g, ok := ds.named[k]
+	if !ok {
+		g = NewGraph()
+		ds.named[k] = g
+		ds.names[k] = name
+	}
+	return g
+}
+
+// LoadAll decodes all the quads from the decoder into the dataset, and
+// returns the number of quads added, that is, not counting quads already in
+// the dataset, nor duplicates within the decoded document.
+//
+// The document is a scope of its own for blank nodes: a blank node label
+// which is already used in the dataset, by an earlier LoadAll or Add, is
+// given a fresh label, so that blank nodes from different documents are never
+// merged. Quads with blank nodes are therefore only deduplicated within the
+// document, while ground quads are deduplicated across loads. Quads in the
+// decoder's default graph are added to the default graph of the dataset.
+//
+// On error, the quads decoded before it remain in the dataset.
+func (ds *Dataset) LoadAll(d *QuadDecoder) (int, error) {
+	scope := make(map[string]Blank) // document label -> dataset blank node
+	relabel := func(b Blank) Blank {
+		if to, ok := scope[b.id]; ok {
+			return to
+		}
+		to := b
+		for ds.blanks[to.id] {
+			to = Blank{id: fmt.Sprintf("_:b%d", ds.nBlank)}
+			ds.nBlank++
+		}
+		scope[b.id] = to
+		ds.blanks[to.id] = true
+		return to
+	}
+
+	n := 0
+	for {
+		q, err := d.Decode()
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		q.Triple = relabelBlanks(q.Triple, relabel)
+		switch {
+		case q.InDefaultGraph(d.DefaultGraph):
+			q.Ctx = ds.DefaultGraph
+		case q.Ctx.Type() == TermBlank:
+			q.Ctx = relabel(q.Ctx.(Blank))
+		}
+		if g := ds.Graph(q.Ctx); g != nil && g.Has(q.Triple) {
+			continue
+		}
+		ds.Add(q)
+		n++
+	}
+}
+
+// Default returns the default graph of the dataset.
+func (ds *Dataset) Default() *Graph {
+	return ds.def
+}
+
+// Graph returns the graph with the given name, or nil if the dataset has
+// no such graph. A nil name, or the DefaultGraph, returns the default graph.
+func (ds *Dataset) Graph(name Context) *Graph {
+	if (Quad{Ctx: name}).InDefaultGraph(ds.DefaultGraph) {
+		return ds.def
+	}
+	return ds.named[name.Serialize(NTriples)]
+}
+
+// Names returns the names of the named graphs in the dataset, sorted by
+// their N-Triples serialization.
+func (ds *Dataset) Names() []Context {
+	keys := make([]string, 0, len(ds.names))
+	for k := range ds.names {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	names := make([]Context, len(keys))
+	for i, k := range keys {
+		names[i] = ds.names[k]
+	}
+	return names
+}
+
+// ToQuads returns all the quads in the dataset: first those of the default
+// graph, with the DefaultGraph as context, then those of the named graphs, in
+// the order of Names. The quads of each graph are sorted as by
+// Graph.ToTriples, so datasets with the same quads always give the same slice.
+func (ds *Dataset) ToQuads() []Quad {
+	qs := make([]Quad, 0, ds.Len())
+	for _, t := range ds.def.ToTriples() {
+		qs = append(qs, Quad{Triple: t, Ctx: ds.DefaultGraph})
+	}
+	for _, name := range ds.Names() {
+		for _, t := range ds.Graph(name).ToTriples() {
+			qs = append(qs, Quad{Triple: t, Ctx: name})
+		}
+	}
+	return qs
+}
+
+// Diff compares ds with other graph by graph, as by Graph.Diff, returning the
+// quads of other which are not in the same graph of ds as added, and the quads
+// of ds which are not in the same graph of other as removed; that is, the
+// changes turning ds into other. A graph in only one of the datasets has all
+// its quads added, or removed. The default graphs are compared with each
+// other, whatever their DefaultGraph; in the returned datasets, the default
+// graph has the DefaultGraph of ds.
+//
+// To apply the changes to ds, remove the triples of each graph of removed from
+// the same graph of ds, then add the quads of added.
+func (ds *Dataset) Diff(other *Dataset) (added, removed *Dataset) {
+	added, removed = NewDataset(), NewDataset()
+	added.DefaultGraph, removed.DefaultGraph = ds.DefaultGraph, ds.DefaultGraph
+
+	diff := func(name Context, a, b *Graph) {
+		if a == nil {
+			a = NewGraph()
+		}
+		if b == nil {
+			b = NewGraph()
+		}
+		add, rem := a.Diff(b)
+		for _, t := range add.triples {
+			added.Add(Quad{Triple: t, Ctx: name})
+		}
+		for _, t := range rem.triples {
+			removed.Add(Quad{Triple: t, Ctx: name})
+		}
+	}
+
+	diff(ds.DefaultGraph, ds.def, other.def)
+	for k, name := range ds.names {
+		diff(name, ds.named[k], other.named[k])
+	}
+	for k, name := range other.names {
+		if _, ok := ds.names[k]; !ok {
+			diff(name, nil, other.named[k])
+		}
+	}
+	return added, removed
+}
+
+// Len returns the number of quads in the dataset.
+func (ds *Dataset) Len() int {
+	n := ds.def.Len()
+	for _, g := range ds.named {
+		n += g.Len()
+	}
+	return n
+}
@@ -0,0 +1,246 @@
+package rdf
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestDatasetBlankGraphLabel(t *testing.T) {
+	input := `<http://example/s> <http://example/p> <http://example/o> .
+<http://example/s> <http://example/p> "in g" _:g .
+_:g <http://example/p> "g as subject" _:g .
+<http://example/s> <http://example/p> "in h" _:h .
+<http://example/s> <http://example/p> "in named" <http://example/g> .
+`
+	qs, err := NewQuadDecoder(bytes.NewBufferString(input), NQuads).DecodeAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ds := NewDataset()
+	ds.Add(qs...)
+
+	if ds.Len() != 5 {
+		t.Errorf("Dataset.Len() => %d; want 5", ds.Len())
+	}
+	if n := ds.Default().Len(); n != 1 {
+		t.Errorf("default graph has %d triples; want 1", n)
+	}
+
+	names := ds.Names()
+	want := []string{"<http://example/g>", "_:g", "_:h"}
+	if len(names) != len(want) {
+		t.Fatalf("Dataset.Names() => %v; want %v", names, want)
+	}
+	for i, name := range names {
+		if name.Serialize(NTriples) != want[i] {
+			t.Errorf("Dataset.Names()[%d] => %v; want %v", i, name.Serialize(NTriples), want[i])
+		}
+	}
+
+	g := ds.Graph(Blank{id: "_:g"})
+	if g == nil || g.Len() != 2 {
+		t.Fatalf("graph _:g => %v; want 2 triples", g)
+	}
+	if !g.Has(Triple{Subj: Blank{id: "_:g"}, Pred: IRI{str: "http://example/p"}, Obj: Literal{str: "g as subject", DataType: xsdString}}) {
+		t.Errorf("graph _:g is missing the triple with the graph label as subject")
+	}
+	if g := ds.Graph(Blank{id: "_:h"}); g == nil || g.Len() != 1 {
+		t.Errorf("graph _:h => %v; want 1 triple", g)
+	}
+	if g := ds.Graph(Blank{id: "_:x"}); g != nil {
+		t.Errorf("graph _:x => %v; want nil", g)
+	}
+	if ds.Graph(nil) != ds.Default() {
+		t.Errorf("Graph(nil) didn't return the default graph")
+	}
+}
+
+func TestDatasetLoadAll(t *testing.T) {
+	dump1 := `<http://ex/s> <http://ex/p> "1" .
+<http://ex/s> <http://ex/p> "1" .
+<http://ex/s> <http://ex/p> "2" <http://ex/g> .
+_:a <http://ex/p> "blank" .
+_:a <http://ex/p> "blank" .
+<http://ex/s> <http://ex/p> "in blank graph" _:a .
+`
+	dump2 := `<http://ex/s> <http://ex/p> "1" .
+<http://ex/s> <http://ex/p> "2" <http://ex/g> .
+<http://ex/s> <http://ex/p> "3" <http://ex/g> .
+_:a <http://ex/p> "blank" .
+_:b0 <http://ex/p> _:a .
+`
+	ds := NewDataset()
+	load := func(input string) int {
+		n, err := ds.LoadAll(NewQuadDecoder(bytes.NewBufferString(input), NQuads))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	if n := load(dump1); n != 4 {
+		t.Errorf("LoadAll(dump1) => %d; want 4", n)
+	}
+	if n := load(dump1); n != 2 {
+		t.Errorf("LoadAll(dump1) again => %d; want 2 (the quads with blank nodes)", n)
+	}
+	if n := load(dump2); n != 3 {
+		t.Errorf("LoadAll(dump2) => %d; want 3", n)
+	}
+	if ds.Len() != 9 {
+		t.Errorf("Dataset.Len() => %d; want 9", ds.Len())
+	}
+
+	// Blank nodes from different documents are kept apart.
+	if got := ds.Default().Match(nil, nil, Literal{str: "blank", DataType: xsdString}); len(got) != 3 {
+		t.Errorf("quads with a blank subject => %v; want 3, one per load", got)
+	}
+	if got := ds.Names(); len(got) != 3 {
+		t.Errorf("Dataset.Names() => %v; want 3 graphs", got)
+	}
+	// _:a and _:b0 of dump2 must not be merged with each other, nor with
+	// any blank node of the earlier loads.
+	for _, tr := range ds.Default().Match(nil, nil, nil) {
+		if o, ok := tr.Obj.(Blank); ok {
+			if s := tr.Subj.(Blank); s.id == o.id {
+				t.Errorf("%v: blank nodes merged", tr)
+			}
+			if len(ds.Default().Match(o, nil, nil)) != 1 {
+				t.Errorf("%v: object should be the subject of exactly one triple", tr)
+			}
+		}
+	}
+
+	if _, err := ds.LoadAll(NewQuadDecoder(bytes.NewBufferString("<http://ex/s> <http://ex/p> \"4\" .\n<http://ex/s> .\n"), NQuads)); err == nil {
+		t.Errorf("LoadAll with invalid input => <no error>; want error")
+	}
+	if ds.Len() != 10 {
+		t.Errorf("after failed LoadAll, Dataset.Len() => %d; want 10", ds.Len())
+	}
+}
+
+func TestDatasetToQuads(t *testing.T) {
+	input := `<http://ex/s> <http://ex/p> "2" <http://ex/g2> .
+<http://ex/s> <http://ex/p> "b" .
+<http://ex/s> <http://ex/p> "1" <http://ex/g1> .
+<http://ex/s> <http://ex/p> "a" .
+<http://ex/s> <http://ex/p> "0" <http://ex/g1> .
+`
+	want := []string{
+		"<http://ex/s> <http://ex/p> \"a\" _:defaultGraph .\n",
+		"<http://ex/s> <http://ex/p> \"b\" _:defaultGraph .\n",
+		"<http://ex/s> <http://ex/p> \"0\" <http://ex/g1> .\n",
+		"<http://ex/s> <http://ex/p> \"1\" <http://ex/g1> .\n",
+		"<http://ex/s> <http://ex/p> \"2\" <http://ex/g2> .\n",
+	}
+
+	ds := NewDataset()
+	if _, err := ds.LoadAll(NewQuadDecoder(bytes.NewBufferString(input), NQuads)); err != nil {
+		t.Fatal(err)
+	}
+	qs := ds.ToQuads()
+	if len(qs) != len(want) {
+		t.Fatalf("ToQuads() => %v; want %d quads", qs, len(want))
+	}
+	for i, q := range qs {
+		if got := q.Serialize(NQuads); got != want[i] {
+			t.Errorf("ToQuads()[%d] => %q; want %q", i, got, want[i])
+		}
+	}
+}
+
+func TestDatasetDiff(t *testing.T) {
+	load := func(input string) *Dataset {
+		ds := NewDataset()
+		if _, err := ds.LoadAll(NewQuadDecoder(bytes.NewBufferString(input), NQuads)); err != nil {
+			t.Fatal(err)
+		}
+		return ds
+	}
+	serialize := func(ds *Dataset) []string {
+		var s []string
+		for _, q := range ds.ToQuads() {
+			s = append(s, q.Serialize(NQuads))
+		}
+		return s
+	}
+
+	before := load(`<http://ex/s> <http://ex/p> "a" .
+<http://ex/s> <http://ex/p> "b" .
+<http://ex/s> <http://ex/p> "1" <http://ex/g1> .
+<http://ex/s> <http://ex/p> "2" <http://ex/g1> .
+<http://ex/s> <http://ex/p> "x" <http://ex/old> .
+`)
+	after := load(`<http://ex/s> <http://ex/p> "a" .
+<http://ex/s> <http://ex/p> "c" .
+<http://ex/s> <http://ex/p> "1" <http://ex/g1> .
+<http://ex/s> <http://ex/p> "a" <http://ex/g1> .
+<http://ex/s> <http://ex/p> "y" <http://ex/new> .
+`)
+
+	added, removed := before.Diff(after)
+	wantAdded := []string{
+		"<http://ex/s> <http://ex/p> \"c\" _:defaultGraph .\n",
+		"<http://ex/s> <http://ex/p> \"a\" <http://ex/g1> .\n",
+		"<http://ex/s> <http://ex/p> \"y\" <http://ex/new> .\n",
+	}
+	wantRemoved := []string{
+		"<http://ex/s> <http://ex/p> \"b\" _:defaultGraph .\n",
+		"<http://ex/s> <http://ex/p> \"2\" <http://ex/g1> .\n",
+		"<http://ex/s> <http://ex/p> \"x\" <http://ex/old> .\n",
+	}
+	if got := serialize(added); !reflect.DeepEqual(got, wantAdded) {
+		t.Errorf("Diff() added =>\n%q\nwant:\n%q", got, wantAdded)
+	}
+	if got := serialize(removed); !reflect.DeepEqual(got, wantRemoved) {
+		t.Errorf("Diff() removed =>\n%q\nwant:\n%q", got, wantRemoved)
+	}
+
+	// Applying the changes turns before into after.
+	for _, name := range append([]Context{nil}, removed.Names()...) {
+		if g := before.Graph(name); g != nil {
+			g.Remove(removed.Graph(name).Triples()...)
+		}
+	}
+	before.Add(added.ToQuads()...)
+	if added, removed := before.Diff(after); added.Len() != 0 || removed.Len() != 0 {
+		t.Errorf("Diff() after applying changes => %v added, %v removed; want none",
+			serialize(added), serialize(removed))
+	}
+}
+
+func TestDatasetEmptyGraph(t *testing.T) {
+	// As decoded from the TriG document
+	//  <http://ex/g1> { <http://ex/s> <http://ex/p> "1" }
+	//  <http://ex/g2> {}
+	//  <http://ex/g3> { <http://ex/s> <http://ex/p> "3" }
+	g1, g2, g3 := IRI{str: "http://ex/g1"}, IRI{str: "http://ex/g2"}, IRI{str: "http://ex/g3"}
+	s, p := IRI{str: "http://ex/s"}, IRI{str: "http://ex/p"}
+	ds := NewDataset()
+	ds.Add(Quad{Triple: Triple{Subj: s, Pred: p, Obj: Literal{str: "1", DataType: xsdString}}, Ctx: g1})
+	if g := ds.AddGraph(g2); g == nil || g.Len() != 0 {
+		t.Fatalf("AddGraph(%v) => %v; want empty graph", g2, g)
+	}
+	ds.Add(Quad{Triple: Triple{Subj: s, Pred: p, Obj: Literal{str: "3", DataType: xsdString}}, Ctx: g3})
+
+	names := ds.Names()
+	if len(names) != 3 || !TermsEqual(names[1], g2) {
+		t.Errorf("Names() => %v; want %v, %v, %v", names, g1, g2, g3)
+	}
+	if g := ds.Graph(g2); g == nil || g.Len() != 0 {
+		t.Errorf("Graph(%v) => %v; want empty graph", g2, g)
+	}
+	if ds.Len() != 2 || len(ds.ToQuads()) != 2 {
+		t.Errorf("Len() => %d, ToQuads() => %v; want 2 quads", ds.Len(), ds.ToQuads())
+	}
+
+	// Adding the graph again keeps its triples.
+	if g := ds.AddGraph(g1); g.Len() != 1 {
+		t.Errorf("AddGraph(%v) on existing graph => %d triples; want 1", g1, g.Len())
+	}
+	if ds.AddGraph(nil) != ds.Default() {
+		t.Errorf("AddGraph(nil) => not the default graph")
+	}
+}
@@ -1,11 +1,78 @@
 package rdf
 
 import (
+	"errors"
 	"fmt"
+	"hash/fnv"
 	"io"
 	"runtime"
 )
 
+// ErrUnexpectedEOF is wrapped by the ParseError returned from a decoder when
+// the input ends in the middle of a term or statement, which usually means
+// that the document has been truncated.
+var ErrUnexpectedEOF = errors.New("unexpected EOF")
+
+// ParseError describes an error encountered by a decoder, which format it was
+// decoding, and where in the input it occured. All errors from parsing the
+// input, except io.EOF, are returned by the decoders of NewTripleDecoder and
+// NewQuadDecoder as a *ParseError, so errors.As can be used to extract it.
+// Other errors, such as an invalid batch size given to DecodeBatch, or the
+// errors raised by wrapping decoders like SchemaDecoder, MapDecoder and
+// SortedCheckDecoder themselves, are not; the wrapping decoders pass on the
+// errors of the inner decoder as they are.
+type ParseError struct {
+	Format Format // format of the document being decoded
+	Line   int    // line number (0 if unknown)
+	Col    int    // column number (NB measured in bytes, not runes)
+	Err    error  // the actual error
+}
+
+// Error returns the error message, prefixed with the format and position of the error.
+func (e *ParseError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%v: %v", e.Format, e.Err)
+	}
+	return fmt.Sprintf("%v: %d:%d: %v", e.Format, e.Line, e.Col, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// formatErr returns the error as a *ParseError from decoding the given format.
+func formatErr(err error, f Format) error {
+	if err == io.EOF {
+		return err
+	}
+	var perr *ParseError
+	if errors.As(err, &perr) {
+		perr.Format = f
+		return err
+	}
+	return &ParseError{Format: f, Err: err}
+}
+
+// unexpectedErr returns a ParseError complaining about the given token,
+// which was not expected in the given context.
+func unexpectedErr(t token, context string) error {
+	var err error
+	switch t.typ {
+	case tokenEOF:
+		err = fmt.Errorf("%w as %s", ErrUnexpectedEOF, context)
+	case tokenErrorEOF:
+		err = fmt.Errorf("%w: syntax error: %s", ErrUnexpectedEOF, t.text)
+	case tokenError:
+		err = fmt.Errorf("syntax error: %s", t.text)
+	case tokenErrorRead:
+		err = fmt.Errorf("read error: %s", t.text)
+	default:
+		err = fmt.Errorf("unexpected %v as %s", t.typ, context)
+	}
+	return &ParseError{Line: t.line, Col: t.col, Err: err}
+}
+
 // A ParseOption allows to customize the behaviour of a decoder.
 type ParseOption int
 
@@ -15,15 +82,92 @@ const (
 	// relative IRIs: Turtle, RDF/XML, TriG, JSON-LD)
 	Base ParseOption = iota
 
+	// MaxTriples is the maximum number of triples to decode. When reached,
+	// the decoder stops parsing and returns io.EOF, as if the document ended
+	// there. Zero means no limit. The option can be changed while decoding,
+	// but once the limit has been reached, the decoder stays at the end of
+	// the document, even if the limit is raised or removed.
+	MaxTriples
+
 	// Strict mode determines how the decoder responds to errors.
 	// When true (the default), it will fail on any malformed input. When
 	// false, it will try to continue parsing, discarding only the malformed
 	// parts.
 	// Strict
 
-	// ErrOut
+	// ErrOut is an io.Writer to write warnings to, one per line. Warnings
+	// are about input which is valid, but likely a mistake, and never stop
+	// the decoding. Currently, the only warning is for blank node labels
+	// occurring only once in the whole document, which is likely a mistyped
+	// label, creating a dangling node. It is written when the end of the
+	// document is reached.
+	ErrOut
 )
 
+// maxTriplesOption validates and returns the value of the MaxTriples option.
+func maxTriplesOption(v interface{}) (int, error) {
+	n, ok := v.(int)
+	if !ok || n < 0 {
+		return 0, fmt.Errorf("ParseOption \"MaxTriples\" must be a non-negative int.")
+	}
+	return n, nil
+}
+
+// errOutOption validates and returns the value of the ErrOut option.
+func errOutOption(v interface{}) (io.Writer, error) {
+	w, ok := v.(io.Writer)
+	if !ok {
+		return nil, fmt.Errorf("ParseOption \"ErrOut\" must be an io.Writer.")
+	}
+	return w, nil
+}
+
+// blankCounter counts the occurrences of the blank node labels in a document,
+// to warn about labels occurring only once. See ErrOut.
+type blankCounter struct {
+	w      io.Writer
+	format Format
+	first  map[string]token // first occurrence of each label
+	count  map[string]int
+	labels []string // labels in order of first occurrence
+	done   bool     // true when the warnings are written
+}
+
+// newBlankCounter returns a blankCounter writing its warnings to w.
+func newBlankCounter(w io.Writer, f Format) *blankCounter {
+	return &blankCounter{w: w, format: f, first: make(map[string]token), count: make(map[string]int)}
+}
+
+// add counts the occurrence of the blank node label of the given token.
+// A nil blankCounter does nothing, so it can be used unconditionally.
+func (c *blankCounter) add(tok token) {
+	if c == nil {
+		return
+	}
+	if c.count[tok.text] == 0 {
+		c.first[tok.text] = tok
+		c.labels = append(c.labels, tok.text)
+	}
+	c.count[tok.text]++
+}
+
+// report writes the warnings, at the end of the document. Subsequent calls
+// do nothing.
+func (c *blankCounter) report() {
+	if c == nil || c.done {
+		return
+	}
+	c.done = true
+	for _, label := range c.labels {
+		if c.count[label] != 1 {
+			continue
+		}
+		tok := c.first[label]
+		w := &ParseError{Format: c.format, Line: tok.line, Col: tok.col, Err: fmt.Errorf("warning: blank node %s occurs only once in the document", label)}
+		fmt.Fprintln(c.w, w)
+	}
+}
+
 // TripleDecoder parses RDF documents (serializations of an RDF graph).
 //
 // For streaming parsing, use the Decode() method to decode a single Triple
@@ -35,15 +179,20 @@ const (
 //  Option      Description        Value      (default)       Format support
 //  ------------------------------------------------------------------------------
 //  Base        Base IRI           IRI        (empty IRI)     Turtle, RDF/XML
+//  MaxTriples  Max triples        int        (0; no limit)   All
 //  Strict      Strict mode        true/false (true)          TODO
-//  ErrOut      Error output       io.Writer  (nil)           TODO
+//  ErrOut      Warning output     io.Writer  (nil)           N-Triples, N-Quads
+//
+// A decoder emits the triples as they occur in the document, including any
+// duplicates. To drop duplicates, either use DecodeUnique (or a DedupDecoder,
+// when streaming), or add the triples to a Graph, which is a set.
 type TripleDecoder interface {
 	// Decode parses a RDF document and return the next valid triple.
 	// It returns io.EOF when the whole document is parsed.
 	Decode() (Triple, error)
 
 	// DecodeAll parses the entire RDF document and return all valid
-	// triples, or an error.
+	// triples, or an error. Duplicate triples are preserved.
 	DecodeAll() ([]Triple, error)
 
 	// SetOption sets a parsing option to the given value. Not all options
@@ -53,6 +202,14 @@ type TripleDecoder interface {
 
 // NewTripleDecoder returns a new TripleDecoder capable of parsing triples
 // from the given io.Reader in the given serialization format.
+//
+// The input must be UTF-8; a leading byte order mark is skipped. To decode
+// input in another encoding, such as Latin-1 or UTF-16, wrap the reader with
+// one transcoding to UTF-8, e.g. a transform.Reader from golang.org/x/text.
+// The input is only read sequentially, so any io.Reader will do. For RDF/XML,
+// the encoding declared in the XML declaration is ignored, since the reader
+// is always taken to give UTF-8. Errors from the reader are returned from the
+// decoder, and do not end the input silently.
 func NewTripleDecoder(r io.Reader, f Format) TripleDecoder {
 	switch f {
 	case NTriples:
@@ -66,6 +223,315 @@ func NewTripleDecoder(r io.Reader, f Format) TripleDecoder {
 	}
 }
 
+// DecodeBatch decodes and returns the next n triples from dec. When the end
+// of the document is reached, the remaining triples, which may be fewer than
+// n, are returned together with io.EOF; the final batch is never deferred to
+// the next call. Once the document is exhausted, DecodeBatch returns a nil
+// slice and io.EOF.
+func DecodeBatch(dec TripleDecoder, n int) ([]Triple, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("DecodeBatch: batch size must be positive, got %d", n)
+	}
+	var ts []Triple
+	for len(ts) < n {
+		t, err := dec.Decode()
+		if err == io.EOF {
+			return ts, io.EOF
+		}
+		if err != nil {
+			return nil, err
+		}
+		if ts == nil {
+			ts = make([]Triple, 0, n)
+		}
+		ts = append(ts, t)
+	}
+	return ts, nil
+}
+
+// DecodeUnique parses the entire RDF document like DecodeAll, but returns
+// each distinct triple only once, in the order they first occur. Unlike
+// DedupDecoder, triples are compared exactly, so the memory cost grows with
+// the size of the distinct triples.
+func DecodeUnique(dec TripleDecoder) ([]Triple, error) {
+	var ts []Triple
+	seen := make(map[string]struct{})
+	for t, err := dec.Decode(); err != io.EOF; t, err = dec.Decode() {
+		if err != nil {
+			return nil, err
+		}
+		k := tripleKey(t)
+		if _, ok := seen[k]; ok {
+			continue
+		}
+		seen[k] = struct{}{}
+		ts = append(ts, t)
+	}
+	return ts, nil
+}
+
+// DedupDecoder returns a TripleDecoder which emits the triples decoded by
+// the inner decoder, suppressing any triple which has already been emitted.
+//
+// Instead of the triples themselves, only a 64-bit FNV-1a hash of each
+// distinct triple is remembered, so the memory cost is about 8 bytes per
+// distinct triple, plus the overhead of the Go map holding them. A
+// consequence is that in the very unlikely event of a hash collision, a
+// distinct triple will be dropped as a duplicate.
+func DedupDecoder(inner TripleDecoder) TripleDecoder {
+	return &dedupDecoder{
+		TripleDecoder: inner,
+		seen:          make(map[uint64]struct{}),
+	}
+}
+
+type dedupDecoder struct {
+	TripleDecoder
+	seen map[uint64]struct{}
+}
+
+// Decode returns the next triple not seen before.
+func (d *dedupDecoder) Decode() (Triple, error) {
+	for {
+		t, err := d.TripleDecoder.Decode()
+		if err != nil {
+			return t, err
+		}
+		h := fnv.New64a()
+		io.WriteString(h, t.Serialize(NTriples))
+		k := h.Sum64()
+		if _, ok := d.seen[k]; ok {
+			continue
+		}
+		d.seen[k] = struct{}{}
+		return t, nil
+	}
+}
+
+// DecodeAll returns all the distinct triples, or an error.
+func (d *dedupDecoder) DecodeAll() ([]Triple, error) {
+	var ts []Triple
+	for t, err := d.Decode(); err != io.EOF; t, err = d.Decode() {
+		if err != nil {
+			return nil, err
+		}
+		ts = append(ts, t)
+	}
+	return ts, nil
+}
+
+// ErrUnknownPredicate is wrapped by the error returned from a SchemaDecoder
+// failing on a triple whose predicate is not in the schema.
+var ErrUnknownPredicate = errors.New("unknown predicate")
+
+// UnknownPredicate determines how a SchemaDecoder handles triples whose
+// predicate is not in the schema.
+type UnknownPredicate int
+
+const (
+	// SkipUnknownPredicates drops the triple, writing a warning to ErrOut
+	// if that option is set.
+	SkipUnknownPredicates UnknownPredicate = iota
+
+	// FailUnknownPredicates returns an error wrapping ErrUnknownPredicate,
+	// which stops the decoding.
+	FailUnknownPredicates
+)
+
+// SchemaDecoder returns a TripleDecoder which emits the triples decoded by
+// the inner decoder whose predicate is one of the given predicates, enforcing
+// a closed vocabulary. Triples with other predicates are handled as given by
+// onUnknown.
+//
+// The ErrOut option is handled by the SchemaDecoder itself, for its warnings
+// about skipped triples; other options are passed on to the inner decoder.
+func SchemaDecoder(inner TripleDecoder, predicates []IRI, onUnknown UnknownPredicate) TripleDecoder {
+	d := &schemaDecoder{
+		TripleDecoder: inner,
+		predicates:    make(map[IRI]struct{}, len(predicates)),
+		onUnknown:     onUnknown,
+	}
+	for _, p := range predicates {
+		d.predicates[p] = struct{}{}
+	}
+	return d
+}
+
+type schemaDecoder struct {
+	TripleDecoder
+	predicates map[IRI]struct{}
+	onUnknown  UnknownPredicate
+	errOut     io.Writer // where to write warnings (nil if not set)
+}
+
+// Decode returns the next triple with a predicate in the schema.
+func (d *schemaDecoder) Decode() (Triple, error) {
+	for {
+		t, err := d.TripleDecoder.Decode()
+		if err != nil {
+			return t, err
+		}
+		if _, ok := d.predicates[t.Pred.(IRI)]; ok {
+			return t, nil
+		}
+		if d.onUnknown == FailUnknownPredicates {
+			return Triple{}, fmt.Errorf("%w %s of subject %s", ErrUnknownPredicate, t.Pred.Serialize(NTriples), t.Subj.Serialize(NTriples))
+		}
+		if d.errOut != nil {
+			fmt.Fprintf(d.errOut, "warning: skipped triple with %s %s of subject %s\n", ErrUnknownPredicate, t.Pred.Serialize(NTriples), t.Subj.Serialize(NTriples))
+		}
+	}
+}
+
+// DecodeAll returns all the triples with a predicate in the schema, or an error.
+func (d *schemaDecoder) DecodeAll() ([]Triple, error) {
+	var ts []Triple
+	for t, err := d.Decode(); err != io.EOF; t, err = d.Decode() {
+		if err != nil {
+			return nil, err
+		}
+		ts = append(ts, t)
+	}
+	return ts, nil
+}
+
+// SetOption sets the ErrOut option for the warnings of the SchemaDecoder,
+// or any other option on the inner decoder.
+func (d *schemaDecoder) SetOption(o ParseOption, v interface{}) error {
+	if o != ErrOut {
+		return d.TripleDecoder.SetOption(o, v)
+	}
+	w, err := errOutOption(v)
+	if err != nil {
+		return err
+	}
+	d.errOut = w
+	return nil
+}
+
+// MapDecoder returns a TripleDecoder which emits the triples decoded by the
+// inner decoder, with each of their terms replaced by rewrite(term). The terms
+// of quoted triples are rewritten too, before the quoted triple itself is
+// given to rewrite. For example, to migrate a namespace while transcoding:
+//
+//	dec := rdf.MapDecoder(inner, func(t rdf.Term) rdf.Term {
+//		if iri, ok := t.(rdf.IRI); ok && strings.HasPrefix(iri.String(), oldNS) {
+//			if migrated, err := rdf.NewIRI(newNS + strings.TrimPrefix(iri.String(), oldNS)); err == nil {
+//				return migrated
+//			}
+//		}
+//		return t // not in oldNS, or not a valid IRI after migration
+//	})
+//
+// Decode returns an error if rewrite returns a term which is not valid in the
+// position of the term it replaces, e.g. a Literal for a subject.
+func MapDecoder(inner TripleDecoder, rewrite func(Term) Term) TripleDecoder {
+	return &mapDecoder{TripleDecoder: inner, rewrite: rewrite}
+}
+
+type mapDecoder struct {
+	TripleDecoder
+	rewrite func(Term) Term
+}
+
+// Decode returns the next triple, rewritten.
+func (d *mapDecoder) Decode() (Triple, error) {
+	t, err := d.TripleDecoder.Decode()
+	if err != nil {
+		return t, err
+	}
+	return d.rewriteTriple(t)
+}
+
+// rewriteTriple returns the triple with its terms rewritten.
+func (d *mapDecoder) rewriteTriple(t Triple) (Triple, error) {
+	var terms [3]Term
+	for i, term := range t.Terms() {
+		if q, ok := term.(QuotedTriple); ok {
+			inner, err := d.rewriteTriple(q.Triple)
+			if err != nil {
+				return Triple{}, err
+			}
+			term = QuotedTriple{inner}
+		}
+		terms[i] = d.rewrite(term)
+	}
+	subj, ok := terms[0].(Subject)
+	if !ok {
+		return Triple{}, fmt.Errorf("MapDecoder: rewrite of subject %v gave invalid subject %v", t.Subj, terms[0])
+	}
+	pred, ok := terms[1].(Predicate)
+	if !ok {
+		return Triple{}, fmt.Errorf("MapDecoder: rewrite of predicate %v gave invalid predicate %v", t.Pred, terms[1])
+	}
+	obj, ok := terms[2].(Object)
+	if !ok {
+		return Triple{}, fmt.Errorf("MapDecoder: rewrite of object %v gave invalid object %v", t.Obj, terms[2])
+	}
+	return Triple{Subj: subj, Pred: pred, Obj: obj}, nil
+}
+
+// DecodeAll returns all the rewritten triples, or an error.
+func (d *mapDecoder) DecodeAll() ([]Triple, error) {
+	var ts []Triple
+	for t, err := d.Decode(); err != io.EOF; t, err = d.Decode() {
+		if err != nil {
+			return nil, err
+		}
+		ts = append(ts, t)
+	}
+	return ts, nil
+}
+
+// ErrUnsorted is wrapped by the error returned from a SortedCheckDecoder
+// when a triple is out of order.
+var ErrUnsorted = errors.New("triples not sorted")
+
+// SortedCheckDecoder returns a TripleDecoder which emits the triples decoded
+// by the inner decoder, checking that they are sorted in canonical order: by
+// subject, then predicate, then object, comparing terms by their N-Triples
+// serialization, as by Graph.ToTriples. Repeated triples are allowed.
+//
+// Decode returns an error wrapping ErrUnsorted for the first triple which
+// sorts before the previous one. Only the previous triple is kept, so the
+// check is cheap and streams any amount of input.
+func SortedCheckDecoder(inner TripleDecoder) TripleDecoder {
+	return &sortedCheckDecoder{TripleDecoder: inner}
+}
+
+type sortedCheckDecoder struct {
+	TripleDecoder
+	prev    Triple
+	hasPrev bool
+	n       int // number of triples decoded
+}
+
+// Decode returns the next triple, or an error if it is out of order.
+func (d *sortedCheckDecoder) Decode() (Triple, error) {
+	t, err := d.TripleDecoder.Decode()
+	if err != nil {
+		return t, err
+	}
+	d.n++
+	if d.hasPrev && bySubjectPredObj([]Triple{d.prev, t}).Less(1, 0) {
+		return Triple{}, fmt.Errorf("%w: triple %d sorts before the previous one:\n%s%s", ErrUnsorted, d.n, d.prev.Serialize(NTriples), t.Serialize(NTriples))
+	}
+	d.prev, d.hasPrev = t, true
+	return t, nil
+}
+
+// DecodeAll returns all the triples, or an error if they are not sorted.
+func (d *sortedCheckDecoder) DecodeAll() ([]Triple, error) {
+	var ts []Triple
+	for t, err := d.Decode(); err != io.EOF; t, err = d.Decode() {
+		if err != nil {
+			return nil, err
+		}
+		ts = append(ts, t)
+	}
+	return ts, nil
+}
+
 // QuadDecoder parses RDF quads in one of the following formats:
 // N-Quads.
 //
@@ -75,19 +541,47 @@ type QuadDecoder struct {
 	l      *lexer
 	format Format
 
-	DefaultGraph Context  // default graph
-	tokens       [3]token // 3 token lookahead
-	peekCount    int      // number of tokens peeked at (position in tokens lookahead array)
+	DefaultGraph Context       // default graph
+	tokens       [3]token      // 3 token lookahead
+	peekCount    int           // number of tokens peeked at (position in tokens lookahead array)
+	max          int           // maximum number of quads to decode (0 for no limit)
+	n            int           // number of quads decoded
+	blanks       *blankCounter // counts blank node labels, when warning about singletons (nil otherwise)
 }
 
 // NewQuadDecoder returns a new QuadDecoder capable of parsing quads
 // from the given io.Reader in the given serialization format.
+// As for NewTripleDecoder, the input must be UTF-8.
 func NewQuadDecoder(r io.Reader, f Format) *QuadDecoder {
 	return &QuadDecoder{
 		l:            newLineLexer(r),
 		format:       f,
-		DefaultGraph: Blank{id: "_:defaultGraph"},
+		DefaultGraph: defaultGraph,
+	}
+}
+
+// SetOption sets a ParseOption to the given value. The QuadDecoder supports
+// the MaxTriples and ErrOut options; see TripleDecoder for a description of
+// the options. MaxTriples limits the number of quads. Blank node labels used
+// as graph names are counted together with those used as subjects and objects.
+func (d *QuadDecoder) SetOption(o ParseOption, v interface{}) error {
+	switch o {
+	case MaxTriples:
+		n, err := maxTriplesOption(v)
+		if err != nil {
+			return err
+		}
+		d.max = n
+	case ErrOut:
+		w, err := errOutOption(v)
+		if err != nil {
+			return err
+		}
+		d.blanks = newBlankCounter(w, d.format)
+	default:
+		return fmt.Errorf("N-Quads decoder doesn't support option: %v", o)
 	}
+	return nil
 }
 
 // Decode returns the next valid Quad, or an error
@@ -107,6 +601,29 @@ func (d *QuadDecoder) DecodeAll() ([]Quad, error) {
 	return qs, nil
 }
 
+// DecodeQuadBatch decodes and returns the next n quads from d. It works like
+// DecodeBatch, but for quads.
+func DecodeQuadBatch(d *QuadDecoder, n int) ([]Quad, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("DecodeQuadBatch: batch size must be positive, got %d", n)
+	}
+	var qs []Quad
+	for len(qs) < n {
+		q, err := d.Decode()
+		if err == io.EOF {
+			return qs, io.EOF
+		}
+		if err != nil {
+			return nil, err
+		}
+		if qs == nil {
+			qs = make([]Quad, 0, n)
+		}
+		qs = append(qs, q)
+	}
+	return qs, nil
+}
+
 // next returns the next token.
 func (d *QuadDecoder) next() token {
 	if d.peekCount > 0 {
@@ -138,7 +655,7 @@ func (d *QuadDecoder) recover(errp *error) {
 			panic(e)
 		}
 		//d.stop() something to clean up?
-		*errp = e.(error)
+		*errp = formatErr(e.(error), d.format)
 	}
 	return
 }
@@ -147,11 +664,7 @@ func (d *QuadDecoder) recover(errp *error) {
 func (d *QuadDecoder) expect1As(context string, expected tokenType) token {
 	t := d.next()
 	if t.typ != expected {
-		if t.typ == tokenError {
-			d.errorf("%d:%d: syntax error: %s", t.line, t.col, t.text)
-		} else {
-			d.unexpected(t, context)
-		}
+		d.unexpected(t, context)
 	}
 	return t
 }
@@ -164,11 +677,7 @@ func (d *QuadDecoder) expectAs(context string, expected ...tokenType) token {
 			return t
 		}
 	}
-	if t.typ == tokenError {
-		d.errorf("%d:%d: syntax error: %v", t.line, t.col, t.text)
-	} else {
-		d.unexpected(t, context)
-	}
+	d.unexpected(t, context)
 	return t
 }
 
@@ -180,5 +689,9 @@ func (d *QuadDecoder) errorf(format string, args ...interface{}) {
 
 // unexpected complains about the given token and terminates parsing.
 func (d *QuadDecoder) unexpected(t token, context string) {
-	d.errorf("%d:%d unexpected %v as %s", t.line, t.col, t.typ, context)
+	if t.typ == tokenEOL && d.peek().typ == tokenEOF {
+		// The line was cut short by the end of input.
+		t.typ = tokenEOF
+	}
+	panic(unexpectedErr(t, context))
 }
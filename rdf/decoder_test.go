@@ -0,0 +1,560 @@
+package rdf
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"unicode/utf8"
+)
+
+func TestDecodeTruncated(t *testing.T) {
+	tests := []struct {
+		format Format
+		input  string
+		eof    bool // true if error should be caused by unexpected EOF
+	}{
+		{NTriples, "<http://ex/s> <http://ex/p> <http://ex/o> .\n<http://ex/s> <http://ex/p> ", true},
+		{NTriples, "<http://ex/s> <http://ex/p> <http://ex/o> .\n<http://ex/s> <http://ex/p> <http://ex/o>", true},
+		{NTriples, "<http://ex/s> <http://ex/p> <http://ex/o> .\n<http://ex/s> <http://ex/p> <http://ex/o", true},
+		{NTriples, "<http://ex/s> <http://ex/p> <http://ex/o> .\n<http://ex/s> <http://ex/p> \"abc", true},
+		{NTriples, "<http://ex/s> <http://ex/p> <http://ex/o> .\n<http://ex/s> <http://ex/p> _:", true},
+		{NTriples, "<http://ex/s> <http://ex/p> .\n<http://ex/s> <http://ex/p> <http://ex/o> .", false},
+		{NTriples, "<http://ex/s> <http://ex/p> \"abc .\n<http://ex/s> <http://ex/p> <http://ex/o> .", false},
+		{Turtle, "<http://ex/s> <http://ex/p> <http://ex/o> .\n<http://ex/s> <http://ex/p> ", true},
+		{Turtle, "<http://ex/s> <http://ex/p> <http://ex/o> .\n<http://ex/s> <http://ex/p> <http://ex/o>", true},
+		{Turtle, "<http://ex/s> <http://ex/p> ( <http://ex/o> ", true},
+		{Turtle, "<http://ex/s> <http://ex/p> [ <http://ex/p> <http://ex/o> ", true},
+		{Turtle, "<http://ex/s> <http://ex/p> \"\"\"abc\ndef", true},
+		{Turtle, "@prefix ex: ", true},
+		{Turtle, "<http://ex/s> <http://ex/p> <http://ex/o> . .", false},
+		{RDFXML, `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"><rdf:Description rdf:about="http://ex/s"><rdf:value>x</rdf:value></rdf:Description>`, true},
+		{RDFXML, `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"><rdf:Description rdf:about="http://ex/s"><rdf:value>x</rdf:`, true},
+	}
+
+	for _, test := range tests {
+		_, err := NewTripleDecoder(bytes.NewBufferString(test.input), test.format).DecodeAll()
+		if err == nil {
+			t.Errorf("decoding %q => <no error>, want error", test.input)
+			continue
+		}
+		if got := errors.Is(err, ErrUnexpectedEOF); got != test.eof {
+			t.Errorf("decoding %q => %v; errors.Is(err, ErrUnexpectedEOF) = %v, want %v", test.input, err, got, test.eof)
+		}
+		if test.eof {
+			var perr *ParseError
+			if !errors.As(err, &perr) {
+				t.Errorf("decoding %q => %v (%T); want *ParseError", test.input, err, err)
+			}
+		}
+	}
+
+	quadTests := []struct {
+		input string
+		eof   bool
+	}{
+		{"<http://ex/s> <http://ex/p> <http://ex/o> <http://ex/g> .\n<http://ex/s> <http://ex/p> <http://ex/o> <http://ex/g>", true},
+		{"<http://ex/s> <http://ex/p> <http://ex/o> <http://ex/g> .\n<http://ex/s> <http://ex/p> <http://ex/o> <http://ex/", true},
+		{"<http://ex/s> <http://ex/p> <http://ex/o> <http://ex/g>\n<http://ex/s> <http://ex/p> <http://ex/o> <http://ex/g> .", false},
+	}
+	for _, test := range quadTests {
+		_, err := NewQuadDecoder(bytes.NewBufferString(test.input), NQuads).DecodeAll()
+		if err == nil {
+			t.Errorf("decoding %q => <no error>, want error", test.input)
+			continue
+		}
+		if got := errors.Is(err, ErrUnexpectedEOF); got != test.eof {
+			t.Errorf("decoding %q => %v; errors.Is(err, ErrUnexpectedEOF) = %v, want %v", test.input, err, got, test.eof)
+		}
+	}
+
+	// A complete document without a trailing newline is not truncated.
+	for _, f := range []Format{NTriples, Turtle} {
+		ts, err := NewTripleDecoder(bytes.NewBufferString("<http://ex/s> <http://ex/p> <http://ex/o> ."), f).DecodeAll()
+		if err != nil || len(ts) != 1 {
+			t.Errorf("decoding complete document => %v, %v; want 1 triple and no error", ts, err)
+		}
+	}
+}
+
+func TestDedupDecoder(t *testing.T) {
+	input := `<http://ex/s> <http://ex/p> <http://ex/o> .
+<http://ex/s> <http://ex/p> "o" .
+<http://ex/s> <http://ex/p> <http://ex/o> .
+<http://ex/s> <http://ex/p> "o"@en .
+<http://ex/s> <http://ex/p> "o"^^<http://www.w3.org/2001/XMLSchema#string> .
+<http://ex/s> <http://ex/p> "o"@en .
+`
+	want := []string{
+		"<http://ex/s> <http://ex/p> <http://ex/o> .\n",
+		"<http://ex/s> <http://ex/p> \"o\" .\n",
+		"<http://ex/s> <http://ex/p> \"o\"@en .\n",
+	}
+
+	all, err := NewTripleDecoder(bytes.NewBufferString(input), NTriples).DecodeAll()
+	if err != nil || len(all) != 6 {
+		t.Fatalf("DecodeAll() => %d triples, %v; want 6 triples", len(all), err)
+	}
+
+	dedup, err := DedupDecoder(NewTripleDecoder(bytes.NewBufferString(input), NTriples)).DecodeAll()
+	if err != nil {
+		t.Fatalf("DedupDecoder.DecodeAll() failed: %v", err)
+	}
+	unique, err := DecodeUnique(NewTripleDecoder(bytes.NewBufferString(input), NTriples))
+	if err != nil {
+		t.Fatalf("DecodeUnique() failed: %v", err)
+	}
+
+	for name, ts := range map[string][]Triple{"DedupDecoder.DecodeAll()": dedup, "DecodeUnique()": unique} {
+		if len(ts) != len(want) {
+			t.Errorf("%s => %d triples; want %d", name, len(ts), len(want))
+			continue
+		}
+		for i, tr := range ts {
+			if got := tr.Serialize(NTriples); got != want[i] {
+				t.Errorf("%s[%d] => %q; want %q", name, i, got, want[i])
+			}
+		}
+	}
+}
+
+func TestSchemaDecoder(t *testing.T) {
+	input := `@prefix ex: <http://ex/> .
+ex:s ex:name "s" ;
+	ex:nmae "typo" ;
+	ex:knows ex:o .
+ex:o ex:name "o" .`
+	schema := []IRI{{str: "http://ex/name"}, {str: "http://ex/knows"}}
+
+	var warnings bytes.Buffer
+	dec := SchemaDecoder(NewTripleDecoder(strings.NewReader(input), Turtle), schema, SkipUnknownPredicates)
+	if err := dec.SetOption(ErrOut, &warnings); err != nil {
+		t.Fatal(err)
+	}
+	if err := dec.SetOption(MaxTriples, 3); err != nil {
+		t.Fatalf("SetOption(MaxTriples) not passed on to inner decoder: %v", err)
+	}
+	ts, err := dec.DecodeAll()
+	if err != nil {
+		t.Fatalf("SchemaDecoder.DecodeAll() failed: %v", err)
+	}
+	if len(ts) != 2 {
+		t.Errorf("SchemaDecoder.DecodeAll() => %v; want 2 triples", ts)
+	}
+	want := "warning: skipped triple with unknown predicate <http://ex/nmae> of subject <http://ex/s>\n"
+	if warnings.String() != want {
+		t.Errorf("SchemaDecoder warnings => %q; want %q", warnings.String(), want)
+	}
+
+	dec = SchemaDecoder(NewTripleDecoder(strings.NewReader(input), Turtle), schema, FailUnknownPredicates)
+	if _, err := dec.Decode(); err != nil {
+		t.Fatalf("SchemaDecoder.Decode() failed: %v", err)
+	}
+	if _, err := dec.Decode(); !errors.Is(err, ErrUnknownPredicate) {
+		t.Errorf("SchemaDecoder.Decode() => %v; want ErrUnknownPredicate", err)
+	}
+}
+
+func TestMapDecoder(t *testing.T) {
+	input := `@prefix old: <http://old.example/> .
+old:s old:p old:o, "lit" .
+<< old:s old:p old:o >> old:p <http://other/x> .`
+
+	migrate := func(t Term) Term {
+		if iri, ok := t.(IRI); ok && strings.HasPrefix(iri.str, "http://old.example/") {
+			return IRI{str: "http://new.example/" + strings.TrimPrefix(iri.str, "http://old.example/")}
+		}
+		return t
+	}
+	ts, err := MapDecoder(NewTripleDecoder(strings.NewReader(input), Turtle), migrate).DecodeAll()
+	if err != nil {
+		t.Fatalf("MapDecoder.DecodeAll() failed: %v", err)
+	}
+	want := []string{
+		"<http://new.example/s> <http://new.example/p> <http://new.example/o> .\n",
+		"<http://new.example/s> <http://new.example/p> \"lit\" .\n",
+		"<< <http://new.example/s> <http://new.example/p> <http://new.example/o> >> <http://new.example/p> <http://other/x> .\n",
+	}
+	if len(ts) != len(want) {
+		t.Fatalf("MapDecoder.DecodeAll() => %v; want %d triples", ts, len(want))
+	}
+	for i, tr := range ts {
+		if got := tr.Serialize(NTriples); got != want[i] {
+			t.Errorf("MapDecoder.DecodeAll()[%d] => %q; want %q", i, got, want[i])
+		}
+	}
+
+	// Composes with the other wrappers.
+	dec := SchemaDecoder(MapDecoder(NewTripleDecoder(strings.NewReader(input), Turtle), migrate), []IRI{{str: "http://new.example/p"}}, FailUnknownPredicates)
+	if ts, err := dec.DecodeAll(); err != nil || len(ts) != 3 {
+		t.Errorf("SchemaDecoder(MapDecoder()).DecodeAll() => %v, %v; want 3 triples", ts, err)
+	}
+
+	toLiteral := func(t Term) Term {
+		if t == Term(IRI{str: "http://old.example/s"}) {
+			return Literal{str: "s", DataType: xsdString}
+		}
+		return t
+	}
+	if _, err := MapDecoder(NewTripleDecoder(strings.NewReader(input), Turtle), toLiteral).Decode(); err == nil {
+		t.Error("MapDecoder rewriting subject to literal => no error; want error")
+	}
+}
+
+func TestSortedCheckDecoder(t *testing.T) {
+	sorted := `<http://ex/a> <http://ex/p> "1" .
+<http://ex/a> <http://ex/p> "1" .
+<http://ex/a> <http://ex/p> "2" .
+<http://ex/a> <http://ex/q> "0" .
+<http://ex/b> <http://ex/p> <http://ex/a> .
+_:x <http://ex/p> "x" .
+`
+	ts, err := SortedCheckDecoder(NewTripleDecoder(strings.NewReader(sorted), NTriples)).DecodeAll()
+	if err != nil || len(ts) != 6 {
+		t.Fatalf("SortedCheckDecoder.DecodeAll() => %d triples, %v; want 6 triples", len(ts), err)
+	}
+
+	// Output encoded in canonical order passes the check.
+	g := NewGraph()
+	g.Add(mustParseTriples(t, `@prefix ex: <http://ex/> .
+ex:z ex:p "z", ex:a, _:b ; ex:a ex:b .
+_:b ex:p ex:z .`)...)
+	var buf bytes.Buffer
+	enc := NewTripleEncoder(&buf, NTriples)
+	if err := enc.EncodeAll(g.ToTriples()); err != nil {
+		t.Fatal(err)
+	}
+	enc.Close()
+	if _, err := SortedCheckDecoder(NewTripleDecoder(&buf, NTriples)).DecodeAll(); err != nil {
+		t.Errorf("SortedCheckDecoder on ToTriples output => %v", err)
+	}
+
+	unsorted := `<http://ex/a> <http://ex/p> "1" .
+<http://ex/a> <http://ex/q> "0" .
+<http://ex/a> <http://ex/p> "2" .
+`
+	dec := SortedCheckDecoder(NewTripleDecoder(strings.NewReader(unsorted), NTriples))
+	ts, err = DecodeBatch(dec, 2)
+	if err != nil || len(ts) != 2 {
+		t.Fatalf("DecodeBatch(SortedCheckDecoder, 2) => %v, %v; want 2 triples", ts, err)
+	}
+	if _, err := dec.Decode(); !errors.Is(err, ErrUnsorted) {
+		t.Errorf("SortedCheckDecoder.Decode() out of order => %v; want ErrUnsorted", err)
+	}
+}
+
+func TestMaxTriples(t *testing.T) {
+	tests := []struct {
+		format Format
+		input  string
+	}{
+		{NTriples, "<http://ex/s> <http://ex/p> \"1\" .\n<http://ex/s> <http://ex/p> \"2\" .\n<http://ex/s> <http://ex/p> \"3\" .\nthis is not N-Triples"},
+		{Turtle, "<http://ex/s> <http://ex/p> \"1\", \"2\", \"3\" .\nthis is not Turtle"},
+		{RDFXML, `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+<rdf:Description rdf:about="http://ex/s"><rdf:value>1</rdf:value><rdf:value>2</rdf:value><rdf:value>3</rdf:value></rdf:Description>
+<this is not RDF/XML`},
+	}
+
+	for _, test := range tests {
+		dec := NewTripleDecoder(bytes.NewBufferString(test.input), test.format)
+		if err := dec.SetOption(MaxTriples, 2); err != nil {
+			t.Fatalf("SetOption(MaxTriples, 2) failed: %v", err)
+		}
+		ts, err := dec.DecodeAll()
+		if err != nil || len(ts) != 2 {
+			t.Errorf("decoding %q with MaxTriples=2 => %d triples, %v; want 2 triples, no error", test.input, len(ts), err)
+		}
+		if _, err := dec.Decode(); err != io.EOF {
+			t.Errorf("Decode() after reaching MaxTriples => %v; want io.EOF", err)
+		}
+
+		if err := NewTripleDecoder(bytes.NewBufferString(test.input), test.format).SetOption(MaxTriples, "2"); err == nil {
+			t.Errorf("SetOption(MaxTriples, \"2\") => <no error>; want error")
+		}
+	}
+
+	qd := NewQuadDecoder(bytes.NewBufferString("<http://ex/s> <http://ex/p> \"1\" <http://ex/g> .\n<http://ex/s> <http://ex/p> \"2\" .\n<http://ex/s> <http://ex/p> \"3\" <http://ex/g> .\nthis is not N-Quads"), NQuads)
+	if err := qd.SetOption(MaxTriples, 2); err != nil {
+		t.Fatalf("QuadDecoder.SetOption(MaxTriples, 2) failed: %v", err)
+	}
+	if qs, err := qd.DecodeAll(); err != nil || len(qs) != 2 {
+		t.Errorf("QuadDecoder.DecodeAll() with MaxTriples=2 => %d quads, %v; want 2 quads, no error", len(qs), err)
+	}
+	if _, err := qd.Decode(); err != io.EOF {
+		t.Errorf("QuadDecoder.Decode() after reaching MaxTriples => %v; want io.EOF", err)
+	}
+}
+
+func TestMaxTriplesChanged(t *testing.T) {
+	inputs := map[Format]string{
+		NTriples: "<http://ex/s> <http://ex/p> \"1\" .\n<http://ex/s> <http://ex/p> \"2\" .\n<http://ex/s> <http://ex/p> \"3\" .\n",
+		Turtle:   "<http://ex/s> <http://ex/p> \"1\", \"2\", \"3\" .",
+		RDFXML: `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+<rdf:Description rdf:about="http://ex/s"><rdf:value>1</rdf:value><rdf:value>2</rdf:value><rdf:value>3</rdf:value></rdf:Description>
+</rdf:RDF>`,
+	}
+
+	for f, input := range inputs {
+		// Raising the limit after it was reached doesn't resume decoding.
+		dec := NewTripleDecoder(bytes.NewBufferString(input), f)
+		dec.SetOption(MaxTriples, 1)
+		if _, err := dec.Decode(); err != nil {
+			t.Fatalf("%v: Decode() => %v", f, err)
+		}
+		dec.SetOption(MaxTriples, 3)
+		if _, err := dec.Decode(); err != io.EOF {
+			t.Errorf("%v: Decode() after raising a reached MaxTriples => %v; want io.EOF", f, err)
+		}
+
+		// Lowering the limit below the number decoded ends decoding.
+		dec = NewTripleDecoder(bytes.NewBufferString(input), f)
+		if _, err := DecodeBatch(dec, 2); err != nil {
+			t.Fatalf("%v: DecodeBatch(dec, 2) => %v", f, err)
+		}
+		dec.SetOption(MaxTriples, 1)
+		if _, err := dec.Decode(); err != io.EOF {
+			t.Errorf("%v: Decode() after lowering MaxTriples => %v; want io.EOF", f, err)
+		}
+	}
+
+	qd := NewQuadDecoder(bytes.NewBufferString(inputs[NTriples]), NQuads)
+	qd.SetOption(MaxTriples, 1)
+	qd.Decode()
+	qd.SetOption(MaxTriples, 0)
+	if _, err := qd.Decode(); err != io.EOF {
+		t.Errorf("QuadDecoder.Decode() after removing a reached MaxTriples => %v; want io.EOF", err)
+	}
+}
+
+func TestDecodeBatch(t *testing.T) {
+	tests := []struct {
+		format Format
+		input  string
+	}{
+		{NTriples, "<http://ex/s> <http://ex/p> \"1\" .\n<http://ex/s> <http://ex/p> \"2\" .\n<http://ex/s> <http://ex/p> \"3\" .\n<http://ex/s> <http://ex/p> \"4\" .\n<http://ex/s> <http://ex/p> \"5\" .\n"},
+		{Turtle, "<http://ex/s> <http://ex/p> \"1\", \"2\", \"3\", \"4\", \"5\" ."},
+		{RDFXML, `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+<rdf:Description rdf:about="http://ex/s"><rdf:value>1</rdf:value><rdf:value>2</rdf:value><rdf:value>3</rdf:value><rdf:value>4</rdf:value><rdf:value>5</rdf:value></rdf:Description>
+</rdf:RDF>`},
+	}
+
+	for _, test := range tests {
+		dec := NewTripleDecoder(bytes.NewBufferString(test.input), test.format)
+		var sizes []int
+		for {
+			ts, err := DecodeBatch(dec, 2)
+			sizes = append(sizes, len(ts))
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("DecodeBatch(2) on %v failed: %v", test.format, err)
+			}
+		}
+		if len(sizes) != 3 || sizes[0] != 2 || sizes[1] != 2 || sizes[2] != 1 {
+			t.Errorf("DecodeBatch(2) on %v => batch sizes %v; want [2 2 1]", test.format, sizes)
+		}
+		if ts, err := DecodeBatch(dec, 2); ts != nil || err != io.EOF {
+			t.Errorf("DecodeBatch(2) after EOF => %v, %v; want nil, io.EOF", ts, err)
+		}
+		if _, err := DecodeBatch(dec, 0); err == nil {
+			t.Errorf("DecodeBatch(0) => <no error>; want error")
+		}
+	}
+
+	qd := NewQuadDecoder(bytes.NewBufferString("<http://ex/s> <http://ex/p> \"1\" <http://ex/g> .\n<http://ex/s> <http://ex/p> \"2\" .\n<http://ex/s> <http://ex/p> \"3\" <http://ex/g> .\n"), NQuads)
+	if qs, err := DecodeQuadBatch(qd, 2); err != nil || len(qs) != 2 {
+		t.Errorf("DecodeQuadBatch(2) => %v, %v; want 2 quads", qs, err)
+	}
+	if qs, err := DecodeQuadBatch(qd, 2); err != io.EOF || len(qs) != 1 {
+		t.Errorf("DecodeQuadBatch(2) => %v, %v; want 1 quad and io.EOF", qs, err)
+	}
+	if _, err := DecodeQuadBatch(qd, 0); err == nil {
+		t.Errorf("DecodeQuadBatch(0) => <no error>; want error")
+	}
+}
+
+func TestParseErrorFormat(t *testing.T) {
+	tests := []struct {
+		format Format
+		input  string
+	}{
+		{NTriples, "<http://ex/s> <http://ex/p> <http://ex/o> <http://ex/o2> .\n"},
+		{Turtle, "@prefix ex: <http://ex/> .\nex:s ex:p ex:o ; ; .\n<s> <p> <o> <o> ."},
+		{Turtle, "ex:s ex:p ex:o ."}, // missing namespace
+		{RDFXML, `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"><rdf:Description></rdf:Other></rdf:RDF>`},
+		{NQuads, "<http://ex/s> <http://ex/p> <http://ex/o> \"g\" .\n"},
+	}
+
+	for _, test := range tests {
+		var err error
+		if test.format == NQuads {
+			_, err = NewQuadDecoder(bytes.NewBufferString(test.input), test.format).DecodeAll()
+		} else {
+			_, err = NewTripleDecoder(bytes.NewBufferString(test.input), test.format).DecodeAll()
+		}
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			t.Errorf("decoding %q => %v (%T); want *ParseError", test.input, err, err)
+			continue
+		}
+		if perr.Format != test.format {
+			t.Errorf("decoding %q => ParseError.Format %v; want %v", test.input, perr.Format, test.format)
+		}
+		if !strings.HasPrefix(err.Error(), test.format.String()+": ") {
+			t.Errorf("decoding %q => %q; want error prefixed with format name %q", test.input, err, test.format)
+		}
+	}
+}
+
+func TestUnterminatedLiteral(t *testing.T) {
+	tests := []struct {
+		format    Format
+		input     string
+		line, col int
+		msg       string
+	}{
+		{NTriples, "<http://ex/s> <http://ex/p> \"a\" .\n<http://ex/s> <http://ex/p> \"abc .\n<http://ex/s> <http://ex/p> \"b\" .\n", 2, 28, "unterminated string literal"},
+		{Turtle, "<http://ex/s> <http://ex/p> 'a' .\n<http://ex/s> <http://ex/p> 'abc ;\n  <http://ex/q> 'b' .\n", 2, 28, "unterminated string literal"},
+		{Turtle, "<http://ex/s> <http://ex/p> \"a\" .\n<http://ex/s> <http://ex/p> \"abc\r\n<http://ex/s> <http://ex/p> \"b\" .\n", 2, 28, "unterminated string literal"},
+		{Turtle, "<http://ex/s> <http://ex/p> \"a\" .\n<http://ex/s> <http://ex/p> \"\"\"abc\n\ndef .\n", 2, 28, "unterminated long string literal"},
+	}
+	for _, test := range tests {
+		_, err := NewTripleDecoder(bytes.NewBufferString(test.input), test.format).DecodeAll()
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			t.Errorf("decoding %q => %v; want *ParseError", test.input, err)
+			continue
+		}
+		if perr.Line != test.line || perr.Col != test.col || !strings.Contains(err.Error(), test.msg) {
+			t.Errorf("decoding %q => %v; want %q at %d:%d", test.input, err, test.msg, test.line, test.col)
+		}
+	}
+}
+
+// latin1Reader transcodes ISO-8859-1 to UTF-8, like a transform.Reader
+// would. It fails on the byte 0xFF, to simulate invalid input.
+type latin1Reader struct {
+	r   io.Reader
+	buf []byte
+}
+
+func (l *latin1Reader) Read(p []byte) (int, error) {
+	if len(l.buf) == 0 {
+		b := make([]byte, len(p)/2+1)
+		n, err := l.r.Read(b)
+		for _, c := range b[:n] {
+			if c == 0xFF {
+				return 0, errors.New("latin1: invalid byte 0xFF")
+			}
+			l.buf = utf8.AppendRune(l.buf, rune(c))
+		}
+		if len(l.buf) == 0 {
+			return 0, err
+		}
+	}
+	n := copy(p, l.buf)
+	l.buf = l.buf[n:]
+	return n, nil
+}
+
+func TestDecodeTranscodedInput(t *testing.T) {
+	want := Literal{str: "Ærlig talt, så é bra", DataType: xsdString}
+	tests := []struct {
+		format Format
+		input  string
+	}{
+		{NTriples, "<http://ex/s> <http://ex/p> \"\xc6rlig talt, s\xe5 \xe9 bra\" .\n"},
+		{Turtle, "@prefix ex: <http://ex/> .\nex:s ex:p '''\xc6rlig talt,\n s\xe5 \xe9 bra'''@no ; ex:p \"\xc6rlig talt, s\xe5 \xe9 bra\" .\n"},
+		{RDFXML, "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>\n<rdf:RDF xmlns:rdf=\"http://www.w3.org/1999/02/22-rdf-syntax-ns#\"><rdf:Description rdf:about=\"http://ex/s\"><p xmlns=\"http://ex/\">\xc6rlig talt, s\xe5 \xe9 bra</p></rdf:Description></rdf:RDF>"},
+	}
+	for _, test := range tests {
+		// Read in small, odd sized chunks, to catch any assumptions about
+		// how much the reader returns.
+		r := iotest.OneByteReader(&latin1Reader{r: iotest.HalfReader(strings.NewReader(test.input))})
+		ts, err := NewTripleDecoder(r, test.format).DecodeAll()
+		if err != nil {
+			t.Errorf("%v: decoding transcoded input => %v", test.format, err)
+			continue
+		}
+		if !TermsEqual(ts[len(ts)-1].Obj, want) {
+			t.Errorf("%v: decoding transcoded input => %v; want object %v", test.format, ts, want)
+		}
+	}
+
+	// The byte order mark is skipped.
+	ts, err := NewTripleDecoder(strings.NewReader("\ufeff<http://ex/s> <http://ex/p> <http://ex/o> .\n"), NTriples).DecodeAll()
+	if err != nil || len(ts) != 1 {
+		t.Errorf("decoding input with byte order mark => %v, %v; want 1 triple", ts, err)
+	}
+
+	// Errors from the reader are reported, and do not end the input silently.
+	for _, f := range []Format{NTriples, Turtle, RDFXML} {
+		input := "<http://ex/s> <http://ex/p> \"a\" .\n<http://ex/s> <http://ex/p> \"\xff\" .\n"
+		if f == RDFXML {
+			input = `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"><rdf:Description rdf:about="http://ex/s"><p xmlns="http://ex/">` + "\xff</p></rdf:Description></rdf:RDF>"
+		}
+		_, err := NewTripleDecoder(&latin1Reader{r: strings.NewReader(input)}, f).DecodeAll()
+		if err == nil || !strings.Contains(err.Error(), "invalid byte 0xFF") {
+			t.Errorf("%v: decoding from failing reader => %v; want read error", f, err)
+		}
+	}
+	qs, err := NewQuadDecoder(&latin1Reader{r: strings.NewReader("<http://ex/s> <http://ex/p> \"\xff\" .\n")}, NQuads).DecodeAll()
+	if err == nil || !strings.Contains(err.Error(), "read error") {
+		t.Errorf("N-Quads: decoding from failing reader => %v, %v; want read error", qs, err)
+	}
+
+	// Untranscoded input is not taken to be UTF-8 silently.
+	if _, err := NewTripleDecoder(strings.NewReader(tests[2].input), RDFXML).DecodeAll(); err == nil {
+		t.Errorf("decoding RDF/XML in ISO-8859-1 without transcoding => <no error>; want error")
+	}
+}
+
+func TestErrOutSingletonBlanks(t *testing.T) {
+	nt := `_:a <http://ex/p> _:b .
+_:b <http://ex/p> "b" .
+<http://ex/s> <http://ex/p> _:typo .
+_:a <http://ex/p> _:c .
+`
+	var warnings bytes.Buffer
+	dec := NewTripleDecoder(strings.NewReader(nt), NTriples)
+	if err := dec.SetOption(ErrOut, &warnings); err != nil {
+		t.Fatal(err)
+	}
+	ts, err := dec.DecodeAll()
+	if err != nil || len(ts) != 4 {
+		t.Fatalf("DecodeAll() => %v, %v; want 4 triples", ts, err)
+	}
+	want := "N-Triples: 3:28: warning: blank node _:typo occurs only once in the document\n" +
+		"N-Triples: 4:18: warning: blank node _:c occurs only once in the document\n"
+	if warnings.String() != want {
+		t.Errorf("warnings =>\n%s\nwant:\n%s", warnings.String(), want)
+	}
+
+	// A blank node label used both as a graph name and a subject or object
+	// is not a singleton.
+	nq := `_:g <http://ex/p> "o" _:g .
+<http://ex/s> <http://ex/p> "o" _:h .
+`
+	warnings.Reset()
+	qdec := NewQuadDecoder(strings.NewReader(nq), NQuads)
+	if err := qdec.SetOption(ErrOut, &warnings); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := qdec.DecodeAll(); err != nil {
+		t.Fatal(err)
+	}
+	want = "N-Quads: 2:32: warning: blank node _:h occurs only once in the document\n"
+	if warnings.String() != want {
+		t.Errorf("warnings =>\n%s\nwant:\n%s", warnings.String(), want)
+	}
+
+	if err := qdec.SetOption(ErrOut, "stderr"); err == nil {
+		t.Errorf("SetOption(ErrOut, string) => <no error>; want error")
+	}
+	if err := NewTripleDecoder(strings.NewReader(""), Turtle).SetOption(ErrOut, &warnings); err == nil {
+		t.Errorf("Turtle SetOption(ErrOut) => <no error>; want error")
+	}
+}
@@ -0,0 +1,100 @@
+package rdf
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/bits"
+)
+
+// ErrNotGround is returned when a triple with a blank node is given
+// to a GroundGraphDigest.
+var ErrNotGround = errors.New("triple is not ground: contains a blank node")
+
+// GroundGraphDigest is an order independent digest of a ground graph, i.e. a
+// graph without blank nodes, which can be updated incrementally as triples
+// are added and removed, without rehashing the whole graph.
+//
+// The digest is the sum, modulo 2^256, of the SHA-256 hash of the N-Triples
+// serialization of each triple. Being a sum, it is commutative over insertion,
+// and removing a triple exactly reverts adding it. Two graphs with the same
+// triples always have the same digest.
+//
+// The digest doesn't keep track of the triples, so the caller must take care
+// not to add a triple which is already included, nor remove one which is not
+// (e.g. by checking Graph.Has before updating the graph and the digest).
+//
+// The zero value is the digest of the empty graph, ready to use.
+type GroundGraphDigest struct {
+	sum [4]uint64 // 256 bit sum, most significant word first
+	n   int       // number of triples
+}
+
+// Add adds the given triples to the digest. It returns ErrNotGround, and
+// leaves the digest unchanged, if any of the triples contains a blank node.
+func (d *GroundGraphDigest) Add(ts ...Triple) error {
+	if err := checkGround(ts); err != nil {
+		return err
+	}
+	for _, t := range ts {
+		h := tripleHash(t)
+		var carry uint64
+		for i := 3; i >= 0; i-- {
+			d.sum[i], carry = bits.Add64(d.sum[i], h[i], carry)
+		}
+		d.n++
+	}
+	return nil
+}
+
+// Remove removes the given triples from the digest. It returns ErrNotGround,
+// and leaves the digest unchanged, if any of the triples contains a blank node.
+func (d *GroundGraphDigest) Remove(ts ...Triple) error {
+	if err := checkGround(ts); err != nil {
+		return err
+	}
+	for _, t := range ts {
+		h := tripleHash(t)
+		var borrow uint64
+		for i := 3; i >= 0; i-- {
+			d.sum[i], borrow = bits.Sub64(d.sum[i], h[i], borrow)
+		}
+		d.n--
+	}
+	return nil
+}
+
+// Len returns the number of triples in the digest.
+func (d *GroundGraphDigest) Len() int {
+	return d.n
+}
+
+// Sum returns the 32 byte digest.
+func (d *GroundGraphDigest) Sum() []byte {
+	b := make([]byte, 32)
+	for i, w := range d.sum {
+		binary.BigEndian.PutUint64(b[i*8:], w)
+	}
+	return b
+}
+
+// checkGround returns ErrNotGround if any of the triples contains a blank node.
+func checkGround(ts []Triple) error {
+	for _, t := range ts {
+		if hasBlank(t) {
+			return ErrNotGround
+		}
+	}
+	return nil
+}
+
+// tripleHash returns the SHA-256 hash of the triple, as 4 words, most
+// significant first.
+func tripleHash(t Triple) [4]uint64 {
+	sum := sha256.Sum256([]byte(t.Serialize(NTriples)))
+	var h [4]uint64
+	for i := range h {
+		h[i] = binary.BigEndian.Uint64(sum[i*8:])
+	}
+	return h
+}
@@ -0,0 +1,65 @@
+package rdf
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGroundGraphDigest(t *testing.T) {
+	ts := mustParseTriples(t, `
+@prefix ex: <http://example.org/> .
+ex:a ex:p ex:b, "b", 1 .
+ex:b ex:p ex:c .
+<< ex:a ex:p ex:b >> ex:q ex:c .`)
+
+	var d1, d2 GroundGraphDigest
+	empty := d1.Sum()
+	if err := d1.Add(ts...); err != nil {
+		t.Fatal(err)
+	}
+	for i := len(ts) - 1; i >= 0; i-- {
+		if err := d2.Add(ts[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !bytes.Equal(d1.Sum(), d2.Sum()) {
+		t.Errorf("digest depends on insertion order: %x != %x", d1.Sum(), d2.Sum())
+	}
+	if d1.Len() != len(ts) {
+		t.Errorf("Len() => %d; want %d", d1.Len(), len(ts))
+	}
+
+	if err := d2.Remove(ts[0]); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(d1.Sum(), d2.Sum()) {
+		t.Errorf("digest unchanged after removing a triple")
+	}
+	var d3 GroundGraphDigest
+	d3.Add(ts[1:]...)
+	if !bytes.Equal(d3.Sum(), d2.Sum()) {
+		t.Errorf("digest after Remove => %x; want %x", d2.Sum(), d3.Sum())
+	}
+	d2.Remove(ts[1:]...)
+	if !bytes.Equal(empty, d2.Sum()) || d2.Len() != 0 {
+		t.Errorf("digest after removing all triples => %x; want %x", d2.Sum(), empty)
+	}
+
+	before := d1.Sum()
+	blanks := mustParseTriples(t, `
+@prefix ex: <http://example.org/> .
+ex:a ex:p ex:b .
+ex:a ex:p _:x .
+<< ex:a ex:p _:y >> ex:q ex:c .`)
+	for _, tr := range blanks[1:] {
+		if err := d1.Add(blanks[0], tr); err != ErrNotGround {
+			t.Errorf("Add(%v) => %v; want ErrNotGround", tr, err)
+		}
+		if err := d1.Remove(tr); err != ErrNotGround {
+			t.Errorf("Remove(%v) => %v; want ErrNotGround", tr, err)
+		}
+	}
+	if !bytes.Equal(before, d1.Sum()) {
+		t.Errorf("digest changed by rejected triples")
+	}
+}
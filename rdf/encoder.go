@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"sort"
+	"strings"
 )
 
 // ErrEncoderClosed is the error returned from Encode() when the Triple/Quad-Encoder is closed
@@ -18,7 +19,13 @@ var ErrEncoderClosed = errors.New("Encoder is closed and cannot encode anymore")
 // For streaming serialization, use the Encode() method to encode a single Triple
 // at a time. Or, if you want to encode multiple triples in one batch, use EncodeAll().
 // In either case; when done serializing, Close() must be called, to ensure
-// that all writes are persisted, since the Encoder uses buffered IO.
+// that all writes are persisted, since the Encoder uses buffered IO. For long
+// running encodings, set FlushEvery, or call Flush(), to make the output
+// available to the underlying writer as it progresses.
+//
+// The output is UTF-8 without a byte order mark, with LF line endings on all
+// platforms; set EOL to CRLF for consumers requiring "\r\n". Line breaks
+// inside long Turtle string literals are part of the literal, and are kept.
 type TripleEncoder struct {
 	format             Format            // Serialization format.
 	w                  *errWriter        // Buffered writer. Set to nil when Encoder is closed.
@@ -29,6 +36,56 @@ type TripleEncoder struct {
 	curPred            Predicate         // Keep track of current subject, to enable encoding of object list.
 	OpenStatement      bool              // True when triple statement hasn't been closed (i.e. in a predicate/object list)
 	GenerateNamespaces bool              // True to auto generate namespaces, false if you give it some custom namespaces and do not want generated ones
+	FlushEvery         int               // Flush the buffered writer every n triples; 0 to flush only on Flush() and Close()
+	CanonicalOrder     bool              // True to sort object lists in EncodeAll (Turtle), false to keep them in the order given
+	QuoteStyle         QuoteStyle        // How to quote string literals (Turtle); defaults to QuoteAuto
+	Collections        bool              // True to write rdf:nil as the empty collection () (Turtle)
+	EOL                EOL               // Line ending; defaults to LF
+	wellKnown          bool              // True to use the customary prefixes of well-known namespaces (EncodeGraph)
+}
+
+// QuoteStyle determines how the Turtle encoder quotes the strings of literals.
+type QuoteStyle int
+
+// Quote styles for string literals.
+const (
+	// QuoteAuto picks the style needing the fewest escapes. Ties are
+	// resolved in favour of "...", then """...""", then '...', and lastly
+	// '''...''', so strings without quotes or line breaks keep the
+	// plain "..." form, while strings with line breaks or double quotes
+	// fall back to """...""".
+	QuoteAuto QuoteStyle = iota
+
+	QuoteDouble     // "..."
+	QuoteLongDouble // """..."""
+	QuoteSingle     // '...'
+	QuoteLongSingle // '''...'''
+)
+
+// EOL is the line ending written by the encoders.
+type EOL int
+
+// Line endings. The encoders write LF by default, on every platform, so the
+// output is the same everywhere, and never write a byte order mark.
+const (
+	LF   EOL = iota // "\n"
+	CRLF            // "\r\n", for consumers requiring it
+)
+
+// nl returns the characters of the line ending.
+func (eol EOL) nl() string {
+	if eol == CRLF {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+// line returns the line s, terminated by "\n", with the line ending.
+func (eol EOL) line(s string) string {
+	if eol == CRLF {
+		return strings.TrimSuffix(s, "\n") + "\r\n"
+	}
+	return s
 }
 
 // NewTripleEncoder returns a new TripleEncoder capable of serializing into the
@@ -50,7 +107,7 @@ func (e *TripleEncoder) Encode(t Triple) error {
 	}
 	switch e.format {
 	case NTriples:
-		_, err := e.w.w.Write([]byte(t.Serialize(e.format)))
+		_, err := e.w.w.Write([]byte(e.EOL.line(t.Serialize(e.format))))
 		if err != nil {
 			return err
 		}
@@ -58,7 +115,7 @@ func (e *TripleEncoder) Encode(t Triple) error {
 		var s, p, o string
 
 		// object is allways rendered the same
-		o = e.prefixify(t.Obj)
+		o = e.node(t.Obj)
 
 		if e.OpenStatement {
 			// potentially predicate/object list
@@ -67,7 +124,7 @@ func (e *TripleEncoder) Encode(t Triple) error {
 				// In predicate or object list
 				if TermsEqual(e.curPred, t.Pred) {
 					// in object list
-					s = " ,\n\t"
+					s = " ," + e.EOL.nl() + "\t"
 					p = ""
 				} else {
 					// in predicate list
@@ -76,29 +133,29 @@ func (e *TripleEncoder) Encode(t Triple) error {
 					// check if predicate introduced new prefix directive
 					if e.OpenStatement {
 						// in predicate list
-						s = " ;\n"
+						s = " ;" + e.EOL.nl()
 						e.curPred = t.Pred
 					} else {
 						// previous statement closed
 						e.curSubj = t.Subj
-						s = e.prefixify(t.Subj)
+						s = e.node(t.Subj)
 						e.curPred = t.Pred
 					}
 				}
 			} else {
 				// not in predicate/ojbect list
 				// close previous statement
-				e.w.write([]byte(" .\n"))
+				e.w.write([]byte(" ." + e.EOL.nl()))
 				e.OpenStatement = false
 				p = e.prefixify(t.Pred)
 				e.curSubj = t.Subj
-				s = e.prefixify(t.Subj)
+				s = e.node(t.Subj)
 				e.curPred = t.Pred
 			}
 		} else {
 			// either first statement, or after a prefix directive
 			p = e.prefixify(t.Pred)
-			s = e.prefixify(t.Subj)
+			s = e.node(t.Subj)
 			e.curSubj = t.Subj
 			e.curPred = t.Pred
 		}
@@ -118,12 +175,52 @@ func (e *TripleEncoder) Encode(t Triple) error {
 	default:
 		panic("TODO")
 	}
-	return nil
+	return e.w.statement(e.FlushEvery)
+}
+
+// EncodeGraph serializes all the triples in the graph, sorted, so that the
+// output is the same for equal graphs.
+//
+// For Turtle, the prefixes of the namespaces used in the graph are discovered
+// up front, and written as a single block before the triples. Namespaces in
+// Namespaces get their given prefix. Well-known namespaces, like those of
+// RDF Schema, OWL, SKOS, FOAF or Dublin Core, get their customary prefix
+// (rdfs, owl, skos, foaf, dcterms, ...), unless Namespaces gives it to another
+// namespace. The other namespaces get generated prefixes, ns0, ns1 and so on,
+// if GenerateNamespaces is true.
+func (e *TripleEncoder) EncodeGraph(g *Graph) error {
+	ts := g.Triples()
+	sort.Slice(ts, func(i, j int) bool { return tripleKey(ts[i]) < tripleKey(ts[j]) })
+	e.wellKnown = true
+	defer func() { e.wellKnown = false }()
+	return e.EncodeAll(ts)
+}
+
+// EncodeGraph writes the graph to w as a Turtle document, with prefixes for
+// the namespaces used; see TripleEncoder.EncodeGraph. To give namespaces
+// prefixes of your own choosing, use a TripleEncoder instead, setting its
+// Namespaces.
+func EncodeGraph(g *Graph, w io.Writer) error {
+	enc := NewTripleEncoder(w, Turtle)
+	if err := enc.EncodeGraph(g); err != nil {
+		return err
+	}
+	return enc.Close()
 }
 
 // EncodeAll serializes a slice of Triples to the io.Writer of the TripleEncoder.
 // It will ignore duplicate triples.
 //
+// For Turtle, all the prefix directives needed by the triples are written up
+// front, as a single block sorted by namespace, so the output is deterministic.
+// Triples annotating another of the triples, i.e. having it as a quoted triple
+// subject, are written with the RDF-star annotation syntax: s p o {| p2 o2 |}
+//
+// The Turtle triples are sorted by subject, then predicate. The objects of a
+// predicate are kept in the order given, unless CanonicalOrder is set, in which
+// case they are sorted too, making the output reproducible regardless of the
+// order of the triples.
+//
 // Note that this function will modify the given slice of triples by sorting it in-place.
 func (e *TripleEncoder) EncodeAll(ts []Triple) error {
 	if e.w == nil {
@@ -132,20 +229,30 @@ func (e *TripleEncoder) EncodeAll(ts []Triple) error {
 	switch e.format {
 	case NTriples:
 		for _, t := range ts {
-			_, err := e.w.w.Write([]byte(t.Serialize(e.format)))
+			_, err := e.w.w.Write([]byte(e.EOL.line(t.Serialize(e.format))))
 			if err != nil {
 				return err
 			}
+			if err := e.w.statement(e.FlushEvery); err != nil {
+				return err
+			}
 		}
 	case Turtle:
 		// Sort triples by Subject, then Predicate, to maximize predicate and object lists.
-		sort.Sort(bySubjectThenPred(triples(ts)))
+		if e.CanonicalOrder {
+			sort.Sort(bySubjectPredObj(triples(ts)))
+		} else {
+			sort.Stable(bySubjectThenPred(triples(ts)))
+		}
+
+		e.writePrefixes(ts)
+		ts, annotations := splitAnnotations(ts)
 
 		var s, p, o string
 
 		for i, t := range ts {
 			// object is allways rendered the same
-			o = e.prefixify(t.Obj)
+			o = e.node(t.Obj)
 
 			if e.OpenStatement {
 				// potentially predicate/object list
@@ -160,7 +267,7 @@ func (e *TripleEncoder) EncodeAll(ts []Triple) error {
 							continue
 						}
 
-						s = " ,\n\t"
+						s = " ," + e.EOL.nl() + "\t"
 						p = ""
 					} else {
 						// in predicate list
@@ -169,29 +276,29 @@ func (e *TripleEncoder) EncodeAll(ts []Triple) error {
 						// check if predicate introduced new prefix directive
 						if e.OpenStatement {
 							// in predicate list
-							s = " ;\n"
+							s = " ;" + e.EOL.nl()
 							e.curPred = t.Pred
 						} else {
 							// previous statement closed
 							e.curSubj = t.Subj
-							s = e.prefixify(t.Subj)
+							s = e.node(t.Subj)
 							e.curPred = t.Pred
 						}
 					}
 				} else {
 					// not in predicate/ojbect list
 					// close previous statement
-					e.w.write([]byte(" .\n"))
+					e.w.write([]byte(" ." + e.EOL.nl()))
 					e.OpenStatement = false
 					p = e.prefixify(t.Pred)
 					e.curSubj = t.Subj
-					s = e.prefixify(t.Subj)
+					s = e.node(t.Subj)
 					e.curPred = t.Pred
 				}
 			} else {
 				// either first statement, or after a prefix directive
 				p = e.prefixify(t.Pred)
-				s = e.prefixify(t.Subj)
+				s = e.node(t.Subj)
 				e.curSubj = t.Subj
 				e.curPred = t.Pred
 			}
@@ -204,9 +311,10 @@ func (e *TripleEncoder) EncodeAll(ts []Triple) error {
 			e.w.write([]byte(p))
 			e.w.write([]byte("\t"))
 			e.w.write([]byte(o))
+			e.writeAnnotation(t, annotations)
 
-			if e.w.err != nil {
-				return e.w.err
+			if err := e.w.statement(e.FlushEvery); err != nil {
+				return err
 			}
 		}
 	default:
@@ -215,6 +323,15 @@ func (e *TripleEncoder) EncodeAll(ts []Triple) error {
 	return nil
 }
 
+// Flush writes any buffered data to the underlying io.Writer. An open Turtle
+// statement is not terminated, as the next triple may continue it.
+func (e *TripleEncoder) Flush() error {
+	if e.w == nil {
+		return ErrEncoderClosed
+	}
+	return e.w.flush()
+}
+
 // Close finalizes an encoding session, ensuring that any concluding tokens are
 // written should it be needed (eg.g close the root tag for RDF/XML) and
 // flushes the underlying buffered writer of the encoder.
@@ -222,7 +339,7 @@ func (e *TripleEncoder) EncodeAll(ts []Triple) error {
 // The encoder cannot encode anymore when Close() has been called.
 func (e *TripleEncoder) Close() error {
 	if e.OpenStatement {
-		e.w.write([]byte(" .")) // Close final statement
+		e.w.write([]byte(" ." + e.EOL.nl())) // Close final statement
 		if e.w.err != nil {
 			return e.w.err
 		}
@@ -232,13 +349,197 @@ func (e *TripleEncoder) Close() error {
 	return err
 }
 
+// writePrefixes writes a prefix directive, sorted by namespace, for every
+// namespace used in the given triples which is not already declared.
+// Generated prefixes are numbered in the same order.
+func (e *TripleEncoder) writePrefixes(ts []Triple) {
+	for _, first := range namespaces(ts) {
+		if _, ok := e.ns[first]; ok {
+			continue
+		}
+		prefix, ok := e.Namespaces[first]
+		if !ok && e.wellKnown {
+			prefix, ok = e.wellKnownPrefix(first)
+		}
+		if !ok {
+			if !e.GenerateNamespaces {
+				continue
+			}
+			prefix = fmt.Sprintf("ns%d", e.nsCount)
+			e.nsCount++
+		}
+		e.ns[first] = prefix
+		if e.OpenStatement {
+			e.w.write([]byte(" ." + e.EOL.nl()))
+			e.OpenStatement = false
+		}
+		e.w.write([]byte(fmt.Sprintf("@prefix %s:\t<%s> .%s", prefix, first, e.EOL.nl())))
+	}
+}
+
+// namespaces returns the namespaces of the IRIs in the triples, which can
+// be abbreviated with a prefix, sorted.
+func namespaces(ts []Triple) []string {
+	seen := make(map[string]bool)
+	var nss []string
+	var add func(Term)
+	add = func(term Term) {
+		if q, ok := term.(QuotedTriple); ok {
+			for _, qt := range q.Terms() {
+				add(qt)
+			}
+			return
+		}
+		first := namespaceOf(term)
+		if first == "" || seen[first] {
+			return
+		}
+		seen[first] = true
+		nss = append(nss, first)
+	}
+	for _, t := range ts {
+		for _, term := range t.Terms() {
+			add(term)
+		}
+	}
+	sort.Strings(nss)
+	return nss
+}
+
+// wellKnownPrefixes maps the namespaces of common vocabularies to their
+// customary prefix.
+var wellKnownPrefixes = map[string]string{
+	"http://www.w3.org/1999/02/22-rdf-syntax-ns#": "rdf",
+	"http://www.w3.org/2000/01/rdf-schema#":       "rdfs",
+	"http://www.w3.org/2001/XMLSchema#":           "xsd",
+	"http://www.w3.org/2002/07/owl#":              "owl",
+	"http://www.w3.org/2004/02/skos/core#":        "skos",
+	"http://www.w3.org/ns/prov#":                  "prov",
+	"http://www.w3.org/ns/shacl#":                 "sh",
+	"http://www.w3.org/ns/dcat#":                  "dcat",
+	"http://xmlns.com/foaf/0.1/":                  "foaf",
+	"http://purl.org/dc/elements/1.1/":            "dc",
+	"http://purl.org/dc/terms/":                   "dcterms",
+	"http://schema.org/":                          "schema",
+	"https://schema.org/":                         "schema",
+}
+
+// wellKnownPrefix returns the customary prefix of the namespace, if it is
+// a well-known one, and the prefix is not taken by another namespace.
+func (e *TripleEncoder) wellKnownPrefix(ns string) (string, bool) {
+	prefix, ok := wellKnownPrefixes[ns]
+	if !ok {
+		return "", false
+	}
+	for other, p := range e.Namespaces {
+		if p == prefix && other != ns {
+			return "", false
+		}
+	}
+	for other, p := range e.ns {
+		if p == prefix && other != ns {
+			return "", false
+		}
+	}
+	return prefix, true
+}
+
+// splitAnnotations separates the triples which have one of the other triples
+// as a quoted triple subject. The annotation triples are returned keyed by
+// the annotated triple (see tripleKey), in the same order as the input.
+func splitAnnotations(ts []Triple) ([]Triple, map[string][]Triple) {
+	asserted := make(map[string]bool, len(ts))
+	for _, t := range ts {
+		asserted[tripleKey(t)] = true
+	}
+	var rest []Triple
+	annotations := make(map[string][]Triple)
+	for _, t := range ts {
+		if q, ok := t.Subj.(QuotedTriple); ok && asserted[tripleKey(q.Triple)] {
+			k := tripleKey(q.Triple)
+			annotations[k] = append(annotations[k], t)
+			continue
+		}
+		rest = append(rest, t)
+	}
+	return rest, annotations
+}
+
+// writeAnnotation writes the annotations of the given triple, if any,
+// as an annotation block: {| p o ; p2 o2 , o3 |}
+func (e *TripleEncoder) writeAnnotation(t Triple, annotations map[string][]Triple) {
+	as := annotations[tripleKey(t)]
+	if len(as) == 0 {
+		return
+	}
+	e.w.write([]byte(" {| "))
+	for i, a := range as {
+		if i > 0 {
+			if TriplesEqual(a, as[i-1]) {
+				continue
+			}
+			if TermsEqual(a.Pred, as[i-1].Pred) {
+				e.w.write([]byte(" , "))
+				e.w.write([]byte(e.node(a.Obj)))
+				e.writeAnnotation(a, annotations)
+				continue
+			}
+			e.w.write([]byte(" ; "))
+		}
+		e.w.write([]byte(e.prefixify(a.Pred)))
+		e.w.write([]byte(" "))
+		e.w.write([]byte(e.node(a.Obj)))
+		e.writeAnnotation(a, annotations)
+	}
+	e.w.write([]byte(" |}"))
+}
+
+// namespaceOf returns the namespace part of the IRI which prefixify would
+// abbreviate for the given term, or an empty string if there is none.
+func namespaceOf(t Term) string {
+	switch term := t.(type) {
+	case IRI:
+		if term == RDFType {
+			return ""
+		}
+		return splitNamespace(term)
+	case Literal:
+		switch term.DataType {
+		case xsdString, xsdInteger, xsdBoolean, xsdDouble, xsdDecimal, rdfLangString:
+			return ""
+		}
+		return splitNamespace(term.DataType)
+	}
+	return ""
+}
+
+// splitNamespace returns the namespace of the IRI, or an empty string if the
+// rest of it cannot be written as a local name.
+func splitNamespace(iri IRI) string {
+	first, rest := iri.Split()
+	if _, ok := escapeLocal(rest); !ok {
+		return ""
+	}
+	return first
+}
+
+// node returns the Turtle representation of a subject or object term. Not for
+// the terms of quoted triples, which cannot be collections.
+func (e *TripleEncoder) node(t Term) string {
+	if e.Collections && t == Term(RDFNil) {
+		return "()"
+	}
+	return e.prefixify(t)
+}
+
 func (e *TripleEncoder) prefixify(t Term) string {
 	if t.Type() == TermIRI {
-		if t.(IRI).str == "http://www.w3.org/1999/02/22-rdf-syntax-ns#type" {
+		if t.(IRI) == RDFType {
 			return "a"
 		}
 		first, rest := t.(IRI).Split()
-		if first == "" {
+		local, ok := escapeLocal(rest)
+		if first == "" || !ok {
 			// cannot split into prefix and namespace
 			return t.Serialize(Turtle)
 		}
@@ -258,22 +559,31 @@ func (e *TripleEncoder) prefixify(t Term) string {
 			}
 			e.ns[first] = prefix
 			if e.OpenStatement {
-				e.w.write([]byte(" .\n"))
+				e.w.write([]byte(" ." + e.EOL.nl()))
 			}
-			e.w.write([]byte(fmt.Sprintf("@prefix %s:\t<%s> .\n", prefix, first)))
+			e.w.write([]byte(fmt.Sprintf("@prefix %s:\t<%s> .%s", prefix, first, e.EOL.nl())))
 			e.OpenStatement = false
 		}
-		return fmt.Sprintf("%s:%s", prefix, rest)
+		return fmt.Sprintf("%s:%s", prefix, local)
+	}
+	if t.Type() == TermQuotedTriple {
+		q := t.(QuotedTriple)
+		return fmt.Sprintf("<< %s %s %s >>", e.prefixify(q.Subj), e.prefixify(q.Pred), e.prefixify(q.Obj))
 	}
 	if t.Type() == TermLiteral {
-		switch t.(Literal).DataType {
-		case xsdString, xsdInteger, xsdBoolean, xsdDouble, xsdDecimal, rdfLangString:
-			// serialize normally in Literal.Serialize method
-			break
+		l := t.(Literal)
+		switch l.DataType {
+		case xsdInteger, xsdBoolean, xsdDouble, xsdDecimal:
+			return l.Serialize(Turtle)
+		case xsdString:
+			return quoteLiteral(l.str, e.QuoteStyle)
+		case rdfLangString:
+			return fmt.Sprintf("%s@%s", quoteLiteral(l.str, e.QuoteStyle), l.Lang())
 		default:
-			first, rest := t.(Literal).DataType.Split()
-			if first == "" {
-				return t.Serialize(Turtle)
+			first, rest := l.DataType.Split()
+			local, ok := escapeLocal(rest)
+			if first == "" || !ok {
+				return fmt.Sprintf("%s^^%s", quoteLiteral(l.str, e.QuoteStyle), l.DataType.Serialize(Turtle))
 			}
 
 			prefix, ok := e.ns[first]
@@ -284,44 +594,120 @@ func (e *TripleEncoder) prefixify(t Term) string {
 					prefix = custom
 				} else {
 					if !e.GenerateNamespaces {
-						return t.Serialize(Turtle)
+						return fmt.Sprintf("%s^^%s", quoteLiteral(l.str, e.QuoteStyle), l.DataType.Serialize(Turtle))
 					}
 					prefix = fmt.Sprintf("ns%d", e.nsCount)
 					e.nsCount++
 				}
 				e.ns[first] = prefix
 				if e.OpenStatement {
-					e.w.write([]byte(" .\n"))
+					e.w.write([]byte(" ." + e.EOL.nl()))
 				}
-				e.w.write([]byte(fmt.Sprintf("@prefix %s:\t<%s> .\n", prefix, first)))
+				e.w.write([]byte(fmt.Sprintf("@prefix %s:\t<%s> .%s", prefix, first, e.EOL.nl())))
 				e.OpenStatement = false
 			}
-			return fmt.Sprintf("\"%s\"^^%s:%s", t.Serialize(formatInternal), prefix, rest)
+			return fmt.Sprintf("%s^^%s:%s", quoteLiteral(l.str, e.QuoteStyle), prefix, local)
 		}
 	}
 	return t.Serialize(Turtle)
 }
 
-func escapeLocal(rest string) string {
-	// escape rest according to PN_LOCAL
-	// http://www.w3.org/TR/turtle/#reserved
+// quoteLiteral returns the string of a literal, escaped and quoted for Turtle
+// in the given style.
+func quoteLiteral(str string, style QuoteStyle) string {
+	switch style {
+	case QuoteDouble:
+		return `"` + escapeQuoted(str, '"', false) + `"`
+	case QuoteLongDouble:
+		return `"""` + escapeQuoted(str, '"', true) + `"""`
+	case QuoteSingle:
+		return `'` + escapeQuoted(str, '\'', false) + `'`
+	case QuoteLongSingle:
+		return `'''` + escapeQuoted(str, '\'', true) + `'''`
+	}
+	best := ""
+	escapes := -1
+	for _, style := range []QuoteStyle{QuoteDouble, QuoteLongDouble, QuoteSingle, QuoteLongSingle} {
+		q := quoteLiteral(str, style)
+		n := len(q) - len(str) - 2
+		if style == QuoteLongDouble || style == QuoteLongSingle {
+			n -= 4
+		}
+		if escapes == -1 || n < escapes {
+			best, escapes = q, n
+		}
+	}
+	return best
+}
+
+// escapeQuoted escapes a string for a Turtle string literal quoted with the
+// given quote character. Long strings keep line feeds as they are, and only
+// escape the quote character where it would otherwise end the string: when
+// it is the third of a run of quotes, or the last character.
+func escapeQuoted(str string, quote rune, long bool) string {
+	var buf bytes.Buffer
+	run := 0 // unescaped quote characters in a row
+	for i, r := range str {
+		switch r {
+		case '\n':
+			if long {
+				buf.WriteRune(r)
+			} else {
+				buf.WriteString(`\n`)
+			}
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case quote:
+			if !long || run == 2 || i == len(str)-1 {
+				buf.WriteRune('\\')
+				buf.WriteRune(r)
+				run = 0
+				continue
+			}
+			buf.WriteRune(r)
+			run++
+			continue
+		default:
+			buf.WriteRune(r)
+		}
+		run = 0
+	}
+	return buf.String()
+}
+
+// escapeLocal escapes rest for use as the local part of a prefixed name,
+// according to PN_LOCAL (http://www.w3.org/TR/turtle/#reserved). Reserved
+// characters, a leading '-' or '.', a trailing '.' and a '%' not starting a
+// percent encoding are escaped with a backslash. It returns false if rest
+// contains characters which cannot occur in a local name, even escaped.
+func escapeLocal(rest string) (string, bool) {
 	var b bytes.Buffer
-	for _, r := range rest {
-		if int(r) <= 126 && int(r) >= 33 {
-			// only bother to check if rune is in range
-			switch r {
-			case '_', '~', '.', '-', '!', '$', '&', '\'', '(', ')', '*', '+', ',', ';', '=', '/', '?', '#', '@', '%':
+	for i, r := range rest {
+		switch {
+		case r == '%':
+			if i+2 >= len(rest) || !isHex(rest[i+1]) || !isHex(rest[i+2]) {
 				b.WriteRune('\\')
-				b.WriteRune(r)
-			default:
-				b.WriteRune(r)
 			}
-		} else {
-			b.WriteRune(r)
+		case r == '.':
+			if i == 0 || i == len(rest)-1 {
+				b.WriteRune('\\')
+			}
+		case r == '-':
+			if i == 0 {
+				b.WriteRune('\\')
+			}
+		case r != '_' && strings.ContainsRune(string(pnLocalEsc[:]), r):
+			b.WriteRune('\\')
+		case isPnCharsU(r) || isDigit(r):
+		case isPnChars(r) && i > 0:
+		default:
+			return "", false
 		}
+		b.WriteRune(r)
 	}
-	// TODO should also ensure that last character is not '.'
-	return b.String()
+	return b.String(), true
 }
 
 type triples []Triple
@@ -352,9 +738,31 @@ func (t bySubjectThenPred) Less(i, j int) bool {
 	}
 }
 
+type bySubjectPredObj triples
+
+func (t bySubjectPredObj) Len() int {
+	return len(t)
+}
+
+func (t bySubjectPredObj) Swap(i, j int) {
+	t[i], t[j] = t[j], t[i]
+}
+
+func (t bySubjectPredObj) Less(i, j int) bool {
+	if bySubjectThenPred(t).Less(i, j) {
+		return true
+	}
+	if bySubjectThenPred(t).Less(j, i) {
+		return false
+	}
+	// subjects and predicates are equal, continue by comparing objects
+	return t[i].Obj.Serialize(NTriples) < t[j].Obj.Serialize(NTriples)
+}
+
 type errWriter struct {
 	w   *bufio.Writer
 	err error
+	n   int // statements written since last flush
 }
 
 func (ew *errWriter) write(buf []byte) {
@@ -364,9 +772,46 @@ func (ew *errWriter) write(buf []byte) {
 	_, ew.err = ew.w.Write(buf)
 }
 
+// statement registers that a statement has been written, and flushes the
+// writer if every (when > 0) statements have been written since the last flush.
+func (ew *errWriter) statement(every int) error {
+	ew.n++
+	if every > 0 && ew.n >= every {
+		return ew.flush()
+	}
+	return ew.err
+}
+
+// flush flushes the buffered writer.
+func (ew *errWriter) flush() error {
+	if ew.err != nil {
+		return ew.err
+	}
+	ew.n = 0
+	ew.err = ew.w.Flush()
+	return ew.err
+}
+
 // QuadEncoder serializes RDF Quads. Currently only supports N-Quads.
+//
+// Quads in the default graph are written without a graph label.
+//
+// When GroupByGraph is set, the quads are buffered until Close() is called,
+// and then written graph by graph, each graph introduced by a "# graph" comment
+// and its triples written without graph label. This is meant as a readable
+// format for human review; the graph labels are lost if decoded as N-Quads.
+//
+// As for the TripleEncoder, lines end with LF unless EOL is set to CRLF.
 type QuadEncoder struct {
 	w *errWriter
+
+	DefaultGraph Context // default graph
+	GroupByGraph bool    // True to write the quads grouped by graph
+	FlushEvery   int     // Flush the buffered writer every n quads; 0 to flush only on Flush() and Close()
+	EOL          EOL     // Line ending; defaults to LF
+
+	graphs []Context           // graphs, in order of first occurence
+	groups map[string][]Triple // graph->triples
 }
 
 // NewQuadEncoder returns a new QuadEncoder on the given writer. The only supported
@@ -376,17 +821,25 @@ func NewQuadEncoder(w io.Writer, f Format) *QuadEncoder {
 		panic("NewQuadEncoder: only N-Quads format supported ATM")
 	}
 	return &QuadEncoder{
-		w: &errWriter{w: bufio.NewWriter(w)},
+		w:            &errWriter{w: bufio.NewWriter(w)},
+		DefaultGraph: defaultGraph,
 	}
 }
 
 // Encode encodes a Quad.
 func (e *QuadEncoder) Encode(q Quad) error {
-	_, err := e.w.w.Write([]byte(q.Serialize(NQuads)))
+	if e.w == nil {
+		return ErrEncoderClosed
+	}
+	if e.GroupByGraph {
+		e.group(q)
+		return nil
+	}
+	_, err := e.w.w.Write([]byte(e.serialize(q)))
 	if err != nil {
 		return err
 	}
-	return nil
+	return e.w.statement(e.FlushEvery)
 }
 
 // EncodeAll encodes all quads.
@@ -395,16 +848,87 @@ func (e *QuadEncoder) EncodeAll(qs []Quad) error {
 		return ErrEncoderClosed
 	}
 	for _, q := range qs {
-		_, err := e.w.w.Write([]byte(q.Serialize(NQuads)))
+		if e.GroupByGraph {
+			e.group(q)
+			continue
+		}
+		_, err := e.w.w.Write([]byte(e.serialize(q)))
 		if err != nil {
 			return err
 		}
+		if err := e.w.statement(e.FlushEvery); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
+// serialize serializes the quad, omitting the graph label if the
+// quad is in the default graph.
+func (e *QuadEncoder) serialize(q Quad) string {
+	if q.InDefaultGraph(e.DefaultGraph) {
+		return e.EOL.line(q.Triple.Serialize(NQuads))
+	}
+	return e.EOL.line(q.Serialize(NQuads))
+}
+
+// group buffers the quad with the other quads in the same graph.
+func (e *QuadEncoder) group(q Quad) {
+	if e.groups == nil {
+		e.groups = make(map[string][]Triple)
+	}
+	g := q.Ctx
+	if q.InDefaultGraph(e.DefaultGraph) {
+		g = nil
+	}
+	k := ""
+	if g != nil {
+		k = g.Serialize(NQuads)
+	}
+	if _, ok := e.groups[k]; !ok {
+		e.graphs = append(e.graphs, g)
+	}
+	e.groups[k] = append(e.groups[k], q.Triple)
+}
+
+// writeGroups writes the buffered quads, graph by graph.
+func (e *QuadEncoder) writeGroups() {
+	for i, g := range e.graphs {
+		if i > 0 {
+			e.w.write([]byte(e.EOL.nl()))
+		}
+		k := ""
+		if g == nil {
+			e.w.write([]byte("# default graph" + e.EOL.nl()))
+		} else {
+			k = g.Serialize(NQuads)
+			e.w.write([]byte("# graph " + k + e.EOL.nl()))
+		}
+		for _, t := range e.groups[k] {
+			e.w.write([]byte(e.EOL.line(t.Serialize(NQuads))))
+		}
+	}
+	e.graphs = nil
+	e.groups = nil
+}
+
+// Flush writes any buffered data to the underlying io.Writer. When
+// GroupByGraph is set, the quads are held back until Close.
+func (e *QuadEncoder) Flush() error {
+	if e.w == nil {
+		return ErrEncoderClosed
+	}
+	return e.w.flush()
+}
+
 // Close closes the encoder and flushes the underlying buffering writer.
 func (e *QuadEncoder) Close() error {
+	if e.GroupByGraph {
+		e.writeGroups()
+		if e.w.err != nil {
+			return e.w.err
+		}
+	}
 	err := e.w.w.Flush()
 	e.w = nil
 	return err
@@ -0,0 +1,365 @@
+package rdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestEncoderFlush(t *testing.T) {
+	var ts []Triple
+	var qs []Quad
+	for i := 0; i < 5; i++ {
+		tr := Triple{
+			Subj: IRI{str: fmt.Sprintf("http://example.org/s%d", i)},
+			Pred: IRI{str: "http://example.org/p"},
+			Obj:  Literal{str: "o", DataType: xsdString},
+		}
+		ts = append(ts, tr)
+		qs = append(qs, Quad{Triple: tr, Ctx: IRI{str: "http://example.org/g"}})
+	}
+	lines := func(buf *bytes.Buffer) int { return bytes.Count(buf.Bytes(), []byte("\n")) }
+
+	for _, format := range []Format{NTriples, Turtle} {
+		var buf bytes.Buffer
+		enc := NewTripleEncoder(&buf, format)
+		enc.FlushEvery = 2
+		for i, tr := range ts {
+			if err := enc.Encode(tr); err != nil {
+				t.Fatal(err)
+			}
+			if buf.Len() == 0 && i >= 1 {
+				t.Errorf("%v: nothing flushed after %d triples with FlushEvery=2", format, i+1)
+			}
+		}
+		if format == NTriples && lines(&buf) != 4 {
+			t.Errorf("%v: %d lines flushed before Close; want 4", format, lines(&buf))
+		}
+		if err := enc.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		flushed := buf.Len()
+		if err := enc.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if format == NTriples && flushed != buf.Len() {
+			t.Errorf("%v: Flush() left %d bytes in the buffer", format, buf.Len()-flushed)
+		}
+		if err := enc.Flush(); err != ErrEncoderClosed {
+			t.Errorf("%v: Flush() after Close() => %v; want ErrEncoderClosed", format, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := NewTripleEncoder(&buf, NTriples)
+	enc.FlushEvery = 3
+	if err := enc.EncodeAll(ts); err != nil {
+		t.Fatal(err)
+	}
+	if lines(&buf) != 3 {
+		t.Errorf("EncodeAll with FlushEvery=3: %d lines flushed before Close; want 3", lines(&buf))
+	}
+
+	buf.Reset()
+	qenc := NewQuadEncoder(&buf, NQuads)
+	qenc.FlushEvery = 2
+	if err := qenc.EncodeAll(qs); err != nil {
+		t.Fatal(err)
+	}
+	if lines(&buf) != 4 {
+		t.Errorf("QuadEncoder with FlushEvery=2: %d lines flushed before Close; want 4", lines(&buf))
+	}
+	if err := qenc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if lines(&buf) != 5 {
+		t.Errorf("QuadEncoder.Flush(): %d lines flushed; want 5", lines(&buf))
+	}
+}
+
+func TestEncoderCanonicalOrder(t *testing.T) {
+	s := IRI{str: "http://example.org/s"}
+	p := IRI{str: "http://example.org/p"}
+	objs := []Object{
+		IRI{str: "http://example.org/c"},
+		Literal{str: "b", DataType: xsdString},
+		IRI{str: "http://example.org/a"},
+		Literal{str: "a", DataType: xsdString},
+	}
+	encode := func(order []int, canonical bool) string {
+		var ts []Triple
+		for _, i := range order {
+			ts = append(ts, Triple{Subj: s, Pred: p, Obj: objs[i]})
+		}
+		var buf bytes.Buffer
+		enc := NewTripleEncoder(&buf, Turtle)
+		enc.CanonicalOrder = canonical
+		if err := enc.EncodeAll(ts); err != nil {
+			t.Fatal(err)
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+
+	want := `@prefix ns0:	<http://example.org/> .
+ns0:s	ns0:p	"a" ,
+			"b" ,
+			ns0:a ,
+			ns0:c .
+`
+	for _, order := range [][]int{{0, 1, 2, 3}, {3, 2, 1, 0}, {2, 0, 3, 1}} {
+		if got := encode(order, true); got != want {
+			t.Errorf("CanonicalOrder, objects in order %v:\ngot:\n%s\nwant:\n%s", order, got, want)
+		}
+	}
+
+	// Without CanonicalOrder, objects keep the order given.
+	want = `@prefix ns0:	<http://example.org/> .
+ns0:s	ns0:p	ns0:c ,
+			"b" ,
+			ns0:a ,
+			"a" .
+`
+	if got := encode([]int{0, 1, 2, 3}, false); got != want {
+		t.Errorf("source order:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestEncoderQuoteStyle(t *testing.T) {
+	tests := []struct {
+		str  string
+		auto string
+	}{
+		{`plain`, `"plain"`},
+		{`say "hi"`, `'say "hi"'`},
+		{"two\nlines", `"""two` + "\n" + `lines"""`},
+		{`it's`, `"it's"`},
+		{`both ' and "`, `'''both ' and "'''`},
+		{`"""`, `'"""'`},
+		{"\"quoted\"\nand 'single'", `""""quoted"` + "\n" + `and 'single'"""`},
+		{`back\slash`, `"back\\slash"`},
+		{"cr\r\nlf", `"""cr\r` + "\n" + `lf"""`},
+		{`""a""b"""`, `'""a""b"""'`},
+	}
+	styles := []QuoteStyle{QuoteAuto, QuoteDouble, QuoteLongDouble, QuoteSingle, QuoteLongSingle}
+	for _, test := range tests {
+		if got := quoteLiteral(test.str, QuoteAuto); got != test.auto {
+			t.Errorf("quoteLiteral(%q, QuoteAuto) => %s; want %s", test.str, got, test.auto)
+		}
+		for _, style := range styles {
+			ts := []Triple{
+				{
+					Subj: IRI{str: "http://example.org/s"},
+					Pred: IRI{str: "http://example.org/p"},
+					Obj:  Literal{str: test.str, DataType: xsdString},
+				},
+				{
+					Subj: IRI{str: "http://example.org/s"},
+					Pred: IRI{str: "http://example.org/q"},
+					Obj:  Literal{str: test.str, DataType: IRI{str: "http://example.org/dt"}},
+				},
+			}
+			var buf bytes.Buffer
+			enc := NewTripleEncoder(&buf, Turtle)
+			enc.QuoteStyle = style
+			if err := enc.EncodeAll(ts); err != nil {
+				t.Fatal(err)
+			}
+			if err := enc.Close(); err != nil {
+				t.Fatal(err)
+			}
+			got, err := NewTripleDecoder(bytes.NewReader(buf.Bytes()), Turtle).DecodeAll()
+			if err != nil || len(got) != 2 || !TermsEqual(got[0].Obj, ts[0].Obj) || !TermsEqual(got[1].Obj, ts[1].Obj) {
+				t.Errorf("QuoteStyle %d, round-trip of %q through:\n%s\n=> %v, %v", style, test.str, buf.String(), got, err)
+			}
+		}
+	}
+}
+
+func TestEncodeGraph(t *testing.T) {
+	g := NewGraph()
+	g.Add(mustParseTriples(t, `
+@prefix ex: <http://example.org/> .
+@prefix foaf: <http://xmlns.com/foaf/0.1/> .
+@prefix rdfs: <http://www.w3.org/2000/01/rdf-schema#> .
+@prefix xsd: <http://www.w3.org/2001/XMLSchema#> .
+ex:alice a foaf:Person ;
+	foaf:name "Alice" ;
+	foaf:knows ex:bob, <http://other.example/carol> ;
+	ex:born "1990-01-01"^^xsd:date .
+ex:bob rdfs:label "Bob" .`)...)
+
+	var buf bytes.Buffer
+	if err := EncodeGraph(g, &buf); err != nil {
+		t.Fatal(err)
+	}
+	want := `@prefix ns0:	<http://example.org/> .
+@prefix ns1:	<http://other.example/> .
+@prefix rdfs:	<http://www.w3.org/2000/01/rdf-schema#> .
+@prefix xsd:	<http://www.w3.org/2001/XMLSchema#> .
+@prefix foaf:	<http://xmlns.com/foaf/0.1/> .
+ns0:alice	ns0:born	"1990-01-01"^^xsd:date ;
+	a	foaf:Person ;
+	foaf:knows	ns0:bob ,
+			ns1:carol ;
+	foaf:name	"Alice" .
+ns0:bob	rdfs:label	"Bob" .
+`
+	if buf.String() != want {
+		t.Errorf("EncodeGraph =>\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	// Pre-registered prefixes take precedence, also over the name of a
+	// well-known prefix.
+	buf.Reset()
+	enc := NewTripleEncoder(&buf, Turtle)
+	enc.Namespaces = map[string]string{
+		"http://example.org/":        "foaf",
+		"http://xmlns.com/foaf/0.1/": "f",
+	}
+	if err := enc.EncodeGraph(g); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, prefix := range []string{
+		"@prefix foaf:\t<http://example.org/> .",
+		"@prefix f:\t<http://xmlns.com/foaf/0.1/> .",
+		"@prefix rdfs:\t<http://www.w3.org/2000/01/rdf-schema#> .",
+	} {
+		if !strings.Contains(buf.String(), prefix) {
+			t.Errorf("EncodeGraph with Namespaces =>\n%s\nwant prefix %s", buf.String(), prefix)
+		}
+	}
+	got := mustParseTriples(t, buf.String())
+	if len(got) != g.Len() {
+		t.Errorf("EncodeGraph round-trip => %d triples; want %d", len(got), g.Len())
+	}
+	for _, tr := range got {
+		if !g.Has(tr) {
+			t.Errorf("EncodeGraph round-trip => %v; not in graph", tr)
+		}
+	}
+}
+
+func TestEncoderLocalNames(t *testing.T) {
+	tests := []struct {
+		local string
+		want  string
+	}{
+		{"123", "ex:123"},
+		{"_foo", "ex:_foo"},
+		{"", "ex:"},
+		{":x", "ex::x"},
+		{"a-b", "ex:a-b"},
+		{"x.y", "ex:x.y"},
+		{"end.", `ex:end\.`},
+		{"-x", `ex:\-x`},
+		{".x", `ex:\.x`},
+		{"a~b", `ex:a\~b`},
+		{"a?b", `ex:a\?b`},
+		{"a%20b", "ex:a%20b"},
+		{"100%", `ex:100\%`},
+		{"é", "ex:é"},
+		{"a b", "<http://example.org/a b>"},
+	}
+
+	for _, test := range tests {
+		iri := IRI{str: "http://example.org/" + test.local}
+		enc := NewTripleEncoder(io.Discard, Turtle)
+		enc.Namespaces = map[string]string{"http://example.org/": "ex"}
+		if got := enc.prefixify(iri); got != test.want {
+			t.Errorf("prefixify(%v) => %s; want %s", iri, got, test.want)
+		}
+		if test.local == "a b" {
+			continue
+		}
+
+		tr := Triple{Subj: iri, Pred: iri, Obj: Literal{str: "x", DataType: iri}}
+		var buf bytes.Buffer
+		enc = NewTripleEncoder(&buf, Turtle)
+		enc.Namespaces = map[string]string{"http://example.org/": "ex"}
+		if err := enc.Encode(tr); err != nil {
+			t.Fatal(err)
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatal(err)
+		}
+		got := mustParseTriples(t, buf.String())
+		if len(got) != 1 || tripleKey(got[0]) != tripleKey(tr) {
+			t.Errorf("round-trip %v =>\n%s\n=> %v", tr, buf.String(), got)
+		}
+	}
+}
+
+func TestEncoderEOL(t *testing.T) {
+	ts := mustParseTriples(t, `@prefix ex: <http://example.org/> .
+ex:s ex:p "one", """two
+lines""" ; ex:q ex:o .
+ex:t ex:p ex:o .`)
+
+	for _, f := range []Format{NTriples, Turtle} {
+		for _, eol := range []EOL{LF, CRLF} {
+			var buf bytes.Buffer
+			enc := NewTripleEncoder(&buf, f)
+			enc.EOL = eol
+			if err := enc.EncodeAll(append([]Triple(nil), ts...)); err != nil {
+				t.Fatal(err)
+			}
+			if err := enc.Close(); err != nil {
+				t.Fatal(err)
+			}
+			out := buf.String()
+			if strings.HasPrefix(out, "\uFEFF") {
+				t.Errorf("%v, EOL %v: output starts with a BOM", f, eol)
+			}
+			if !strings.HasSuffix(out, eol.nl()) || strings.HasSuffix(out, eol.nl()+eol.nl()) {
+				t.Errorf("%v, EOL %v: last line not ended by a single line break:\n%q", f, eol, out)
+			}
+			crlfs := strings.Count(out, "\r\n")
+			lfs := strings.Count(out, "\n") - crlfs
+			switch {
+			case eol == LF && crlfs != 0:
+				t.Errorf("%v, EOL LF: output has CRLF:\n%q", f, out)
+			case eol == CRLF && f == NTriples && lfs != 0:
+				t.Errorf("%v, EOL CRLF: output has LF:\n%q", f, out)
+			case eol == CRLF && f == Turtle && lfs != 1: // the line break in the literal
+				t.Errorf("%v, EOL CRLF: output has %d LF; want 1 in the literal:\n%q", f, lfs, out)
+			}
+
+			got, err := NewTripleDecoder(strings.NewReader(out), f).DecodeAll()
+			if err != nil {
+				t.Fatalf("%v, EOL %v: decoding %q failed: %v", f, eol, out, err)
+			}
+			if len(got) != len(ts) {
+				t.Errorf("%v, EOL %v: round-trip => %v; want %v", f, eol, got, ts)
+				continue
+			}
+			for i := range got {
+				if !TriplesEqual(got[i], ts[i]) {
+					t.Errorf("%v, EOL %v: round-trip => %v; want %v", f, eol, got[i], ts[i])
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := NewQuadEncoder(&buf, NQuads)
+	enc.EOL = CRLF
+	enc.GroupByGraph = true
+	for _, tr := range ts {
+		enc.Encode(Quad{Triple: tr, Ctx: IRI{str: "http://example.org/g"}})
+	}
+	enc.Encode(Quad{Triple: ts[0], Ctx: enc.DefaultGraph})
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if out := buf.String(); strings.Count(out, "\n") != strings.Count(out, "\r\n") {
+		t.Errorf("QuadEncoder, EOL CRLF: output has LF:\n%q", out)
+	}
+}
@@ -0,0 +1,178 @@
+package rdf
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// sniffLen is the number of bytes DetectFormat is given to look at, when
+// the format cannot be determined from the file name. If there is no line
+// break within them, more is looked at, up to maxSniffLen bytes.
+const (
+	sniffLen    = 1024
+	maxSniffLen = 64 * 1024
+)
+
+// DetectFormat returns the serialization format of a document, given its file
+// name and the first bytes of its content. The format is determined by the
+// file extension when it is a known one:
+//
+//	.nt         N-Triples
+//	.ttl        Turtle
+//	.rdf, .owl  RDF/XML
+//	.nq         N-Quads
+//
+// Otherwise the content is inspected: XML is taken to be RDF/XML, while line
+// based content is taken to be N-Triples or N-Quads if every line of head
+// parses as such, or else Turtle, of which N-Triples is a subset. Unless head
+// holds the whole content, it should therefore end with a line break, so that
+// its last line is not cut short.
+func DetectFormat(name string, head []byte) (Format, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".nt":
+		return NTriples, nil
+	case ".ttl":
+		return Turtle, nil
+	case ".rdf", ".owl":
+		return RDFXML, nil
+	case ".nq":
+		return NQuads, nil
+	}
+
+	head = bytes.TrimPrefix(head, []byte("\xef\xbb\xbf")) // UTF-8 BOM
+	head = bytes.TrimLeft(head, " \t\r\n")
+	if len(head) == 0 {
+		return 0, fmt.Errorf("cannot detect format of %q: no content", name)
+	}
+	if bytes.HasPrefix(head, []byte("<?")) || bytes.HasPrefix(head, []byte("<!")) || bytes.HasPrefix(head, []byte("<rdf:RDF")) {
+		return RDFXML, nil
+	}
+
+	for _, line := range strings.Split(string(head), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if isTurtleDirective(line) {
+			return Turtle, nil
+		}
+		break
+	}
+	return sniffStatements(string(head)), nil
+}
+
+// isTurtleDirective returns true if the line starts with a Turtle prefix
+// or base directive.
+func isTurtleDirective(line string) bool {
+	for _, d := range []string{"@prefix", "@base", "prefix", "base"} {
+		if len(line) > len(d) && strings.EqualFold(line[:len(d)], d) && (line[len(d)] == ' ' || line[len(d)] == '\t') {
+			return true
+		}
+	}
+	return false
+}
+
+// sniffStatements returns the format of lines holding statements: N-Quads if
+// they are all quads, some of them in a named graph, N-Triples if they are all
+// triples, or else Turtle.
+func sniffStatements(lines string) Format {
+	qd := NewQuadDecoder(strings.NewReader(lines+"\n"), NQuads)
+	qs, err := qd.DecodeAll()
+	qd.l.stop()
+	if err != nil || len(qs) == 0 {
+		return Turtle
+	}
+	for _, q := range qs {
+		if !q.InDefaultGraph(qd.DefaultGraph) {
+			return NQuads
+		}
+	}
+	return NTriples
+}
+
+// DecodeFile decodes all the triples in the file at the given path. The format
+// is detected by DetectFormat, and gzip-compressed files are transparently
+// decompressed, in which case the format is detected from the file name without
+// the .gz extension. The file is always closed before DecodeFile returns.
+//
+// Files in a quad format must be decoded with DecodeFileQuads.
+func DecodeFile(path string) ([]Triple, error) {
+	var ts []Triple
+	err := decodeFile(path, func(r io.Reader, f Format) (err error) {
+		if f == NQuads {
+			return fmt.Errorf("%s: %v is a quad format; use DecodeFileQuads", path, f)
+		}
+		ts, err = NewTripleDecoder(r, f).DecodeAll()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ts, nil
+}
+
+// DecodeFileQuads decodes all the quads in the file at the given path. It works
+// like DecodeFile, but for quad formats; files in a triple format must be decoded
+// with DecodeFile.
+func DecodeFileQuads(path string) ([]Quad, error) {
+	var qs []Quad
+	err := decodeFile(path, func(r io.Reader, f Format) (err error) {
+		if f != NQuads {
+			return fmt.Errorf("%s: %v is a triple format; use DecodeFile", path, f)
+		}
+		qs, err = NewQuadDecoder(r, f).DecodeAll()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return qs, nil
+}
+
+// decodeFile opens the file at path, decompressing it if gzipped, detects its
+// format, and passes it on to the decode function. The file is closed when
+// decode returns.
+func decodeFile(path string, decode func(io.Reader, Format) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	name := path
+	br := bufio.NewReaderSize(file, maxSniffLen)
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		defer zr.Close()
+		br = bufio.NewReaderSize(zr, maxSniffLen)
+		if strings.EqualFold(filepath.Ext(name), ".gz") {
+			name = name[:len(name)-len(".gz")]
+		}
+	}
+
+	// Peek returns an error when the content is shorter than asked for,
+	// in which case head holds all of it. Otherwise the last line of head
+	// may be cut short, and is left out.
+	head, err := br.Peek(sniffLen)
+	for n := sniffLen; err == nil && bytes.IndexByte(head, '\n') < 0 && n < maxSniffLen; {
+		n *= 2
+		head, err = br.Peek(n)
+	}
+	if i := bytes.LastIndexByte(head, '\n'); err == nil && i >= 0 {
+		head = head[:i+1]
+	}
+	f, err := DetectFormat(name, head)
+	if err != nil {
+		return err
+	}
+	return decode(br, f)
+}
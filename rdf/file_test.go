@@ -0,0 +1,95 @@
+package rdf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		head string
+		want Format
+	}{
+		{"data.nt", "", NTriples},
+		{"data.TTL", "", Turtle},
+		{"data.owl", "", RDFXML},
+		{"data.nq", "", NQuads},
+		{"data", `<?xml version="1.0"?><rdf:RDF>`, RDFXML},
+		{"data", "\n<rdf:RDF xmlns:rdf=\"http://www.w3.org/1999/02/22-rdf-syntax-ns#\">", RDFXML},
+		{"data", "# comment\n@prefix ex: <http://ex/> .\n", Turtle},
+		{"data", "PREFIX ex: <http://ex/>\n", Turtle},
+		{"data", "<http://ex/s> <http://ex/p> \"o\" .\n<http://ex/s> <http://ex/p> \"o2\" .\n", NTriples},
+		{"data", "_:s <http://ex/p> <http://ex/o> <http://ex/g> .\n", NQuads},
+		{"data", "<http://ex/s> <http://ex/p> <http://ex/o>, <http://ex/o2> .\n", Turtle},
+		{"data", "<http://ex/s> <http://ex/p> <http://ex/o> .\n<http://ex/s> a <http://ex/C> .", Turtle},
+		{"data", "# comment\n<http://ex/s> <http://ex/p> <http://ex/o> .\n_:s <http://ex/p> \"o\" <http://ex/g> .\n", NQuads},
+	}
+	for _, tt := range tests {
+		got, err := DetectFormat(tt.name, []byte(tt.head))
+		if err != nil || got != tt.want {
+			t.Errorf("DetectFormat(%q, %q) => %v, %v; want %v", tt.name, tt.head, got, err, tt.want)
+		}
+	}
+	if _, err := DetectFormat("data", []byte(" \n")); err == nil {
+		t.Errorf("DetectFormat with no content => <no error>; want error")
+	}
+}
+
+func TestDecodeFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string, gz bool) string {
+		path := filepath.Join(dir, name)
+		var buf bytes.Buffer
+		if gz {
+			zw := gzip.NewWriter(&buf)
+			zw.Write([]byte(content))
+			zw.Close()
+		} else {
+			buf.WriteString(content)
+		}
+		if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	ttl := write("data.ttl", "@prefix ex: <http://ex/> .\nex:s ex:p ex:o, ex:o2 .\n", false)
+	ntgz := write("data.nt.gz", "<http://ex/s> <http://ex/p> <http://ex/o> .\n", true)
+	gzNoExt := write("data.gz", "<http://ex/s> <http://ex/p> <http://ex/o> <http://ex/g> .\n", true)
+	mixed := write("data", "<http://ex/s> <http://ex/p> <http://ex/o> .\n<http://ex/s> a <http://ex/C> .\n", false)
+	longLine := write("long", "<http://ex/s> <http://ex/p> \""+strings.Repeat("x", 2*sniffLen)+"\" <http://ex/g> .\n", false)
+	bad := write("bad.ttl", "@prefix ex: <http://ex/> .\nex:s ex:p .\n", false)
+
+	if ts, err := DecodeFile(ttl); err != nil || len(ts) != 2 {
+		t.Errorf("DecodeFile(%q) => %d triples, %v; want 2 triples", ttl, len(ts), err)
+	}
+	if ts, err := DecodeFile(ntgz); err != nil || len(ts) != 1 {
+		t.Errorf("DecodeFile(%q) => %d triples, %v; want 1 triple", ntgz, len(ts), err)
+	}
+	if qs, err := DecodeFileQuads(gzNoExt); err != nil || len(qs) != 1 {
+		t.Errorf("DecodeFileQuads(%q) => %d quads, %v; want 1 quad", gzNoExt, len(qs), err)
+	}
+	if _, err := DecodeFile(gzNoExt); err == nil || !strings.Contains(err.Error(), "DecodeFileQuads") {
+		t.Errorf("DecodeFile(%q) => %v; want error suggesting DecodeFileQuads", gzNoExt, err)
+	}
+	if ts, err := DecodeFile(mixed); err != nil || len(ts) != 2 {
+		t.Errorf("DecodeFile(%q) => %d triples, %v; want 2 triples", mixed, len(ts), err)
+	}
+	if qs, err := DecodeFileQuads(longLine); err != nil || len(qs) != 1 {
+		t.Errorf("DecodeFileQuads(%q) => %d quads, %v; want 1 quad", longLine, len(qs), err)
+	}
+	if _, err := DecodeFileQuads(ttl); err == nil {
+		t.Errorf("DecodeFileQuads(%q) => <no error>; want error", ttl)
+	}
+	if _, err := DecodeFile(bad); err == nil {
+		t.Errorf("DecodeFile(%q) => <no error>; want error", bad)
+	}
+	if _, err := DecodeFile(filepath.Join(dir, "missing.ttl")); !os.IsNotExist(err) {
+		t.Errorf("DecodeFile on missing file => %v; want not exist error", err)
+	}
+}
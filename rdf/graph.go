@@ -0,0 +1,443 @@
+package rdf
+
+import (
+	"sort"
+	"sync"
+)
+
+// Graph is an in-memory set of triples. Adding a triple which is already
+// in the graph has no effect.
+//
+// A Graph is not safe for concurrent use; wrap it in a SyncGraph if it is to
+// be accessed from multiple goroutines.
+type Graph struct {
+	// NormalizeIRIs makes Match compare IRIs after normalizing them with
+	// NormalizeIRI, so that a pattern matches stored IRIs which differ only
+	// in case or percent-encoding. Defaults to false (exact matching).
+	NormalizeIRIs bool
+
+	triples map[string]Triple
+}
+
+// NewGraph returns a new, empty Graph.
+func NewGraph() *Graph {
+	return &Graph{triples: make(map[string]Triple)}
+}
+
+// tripleKey returns the key used to identify a triple in a Graph.
+func tripleKey(t Triple) string {
+	return t.Serialize(NTriples)
+}
+
+// Add adds the given triples to the graph.
+func (g *Graph) Add(ts ...Triple) {
+	for _, t := range ts {
+		g.triples[tripleKey(t)] = t
+	}
+}
+
+// Remove removes the given triples from the graph. Triples not in
+// the graph are ignored.
+func (g *Graph) Remove(ts ...Triple) {
+	for _, t := range ts {
+		delete(g.triples, tripleKey(t))
+	}
+}
+
+// Has returns true if the triple is in the graph.
+func (g *Graph) Has(t Triple) bool {
+	_, ok := g.triples[tripleKey(t)]
+	return ok
+}
+
+// Len returns the number of triples in the graph.
+func (g *Graph) Len() int {
+	return len(g.triples)
+}
+
+// Triples returns all the triples in the graph, in no particular order.
+func (g *Graph) Triples() []Triple {
+	ts := make([]Triple, 0, len(g.triples))
+	for _, t := range g.triples {
+		ts = append(ts, t)
+	}
+	return ts
+}
+
+// ToTriples returns all the triples in the graph, sorted by subject, predicate
+// and object, comparing terms by their N-Triples serialization. Graphs with
+// the same triples always give the same slice.
+func (g *Graph) ToTriples() []Triple {
+	ts := g.Triples()
+	sort.Sort(bySubjectPredObj(ts))
+	return ts
+}
+
+// Diff compares g with other, returning the triples of other which are not in
+// g as added, and the triples of g which are not in other as removed; that is,
+// the changes turning g into other. Blank nodes are compared by label, as by
+// Has, so the same structure with other blank node labels is a difference.
+func (g *Graph) Diff(other *Graph) (added, removed *Graph) {
+	added, removed = NewGraph(), NewGraph()
+	for k, t := range other.triples {
+		if _, ok := g.triples[k]; !ok {
+			added.triples[k] = t
+		}
+	}
+	for k, t := range g.triples {
+		if _, ok := other.triples[k]; !ok {
+			removed.triples[k] = t
+		}
+	}
+	return added, removed
+}
+
+// Match returns all triples in the graph matching the given pattern, in no
+// particular order. A nil subject, predicate or object acts as a wildcard.
+//
+// A QuotedTriple pattern matches quoted triples, and may itself contain nil
+// terms acting as wildcards; e.g. QuotedTriple{Triple{Subj: s}} matches any
+// quoted triple with the subject s.
+//
+// IRIs must match exactly, unless NormalizeIRIs is set.
+func (g *Graph) Match(s Subject, p Predicate, o Object) []Triple {
+	matches := termMatches
+	if g.NormalizeIRIs {
+		matches = termMatchesNormalized
+	}
+	var ts []Triple
+	for _, t := range g.triples {
+		if s != nil && !matches(s, t.Subj) {
+			continue
+		}
+		if p != nil && !matches(p, t.Pred) {
+			continue
+		}
+		if o != nil && !matches(o, t.Obj) {
+			continue
+		}
+		ts = append(ts, t)
+	}
+	return ts
+}
+
+// Annotations returns the triples annotating the triple t, that is, the
+// triples having << t >> as subject, in no particular order. The triple t
+// itself need not be in the graph.
+func (g *Graph) Annotations(t Triple) []Triple {
+	return g.Match(QuotedTriple{t}, nil, nil)
+}
+
+// SubgraphForSubjects returns a new graph with the triples of g whose subject
+// is one of the given subjects. If followBlanks is true, the triples of blank
+// nodes in object position, including inside quoted triples, are added too,
+// recursively, like a concise bounded description: the result then describes
+// the subjects completely, without dangling blank nodes. Otherwise only the
+// triples of the subjects themselves are added.
+func (g *Graph) SubgraphForSubjects(subjects []Subject, followBlanks bool) *Graph {
+	bySubj := make(map[string][]Triple)
+	for _, t := range g.triples {
+		k := t.Subj.Serialize(NTriples)
+		bySubj[k] = append(bySubj[k], t)
+	}
+
+	sub := NewGraph()
+	sub.NormalizeIRIs = g.NormalizeIRIs
+	seen := make(map[string]bool)
+	queue := append([]Subject(nil), subjects...)
+	for len(queue) > 0 {
+		k := queue[0].Serialize(NTriples)
+		queue = queue[1:]
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		for _, t := range bySubj[k] {
+			sub.Add(t)
+			if !followBlanks {
+				continue
+			}
+			switch o := t.Obj.(type) {
+			case Blank:
+				queue = append(queue, o)
+			case QuotedTriple:
+				for _, b := range blanksOf(o.Triple) {
+					queue = append(queue, b)
+				}
+			}
+		}
+	}
+	return sub
+}
+
+// DanglingBlanks returns the blank nodes which occur in the graph only as
+// subjects, or only as objects, sorted by identifier. A blank node which is
+// the object of some triple, but never described by any triples of its own,
+// or the other way around, is often the result of a broken transformation.
+//
+// A blank node inside a quoted triple, in either position, is referenced but
+// not described by it, so it counts as an object.
+func (g *Graph) DanglingBlanks() []Blank {
+	subj := make(map[string]Blank)
+	obj := make(map[string]Blank)
+	for _, t := range g.triples {
+		switch term := t.Subj.(type) {
+		case Blank:
+			subj[term.id] = term
+		case QuotedTriple:
+			for _, b := range blanksOf(term.Triple) {
+				obj[b.id] = b
+			}
+		}
+		switch term := t.Obj.(type) {
+		case Blank:
+			obj[term.id] = term
+		case QuotedTriple:
+			for _, b := range blanksOf(term.Triple) {
+				obj[b.id] = b
+			}
+		}
+	}
+	var bs []Blank
+	for id, b := range subj {
+		if _, ok := obj[id]; !ok {
+			bs = append(bs, b)
+		}
+	}
+	for id, b := range obj {
+		if _, ok := subj[id]; !ok {
+			bs = append(bs, b)
+		}
+	}
+	sort.Slice(bs, func(i, j int) bool { return bs[i].id < bs[j].id })
+	return bs
+}
+
+// RenameBlank replaces every occurrence of the blank node old, as subject or
+// object, including inside quoted triples, with the blank node new. It returns
+// the number of triples changed.
+//
+// If new already occurs in the graph, the two blank nodes are merged, and
+// triples which become equal are stored once, so the graph may shrink.
+func (g *Graph) RenameBlank(old, new Blank) int {
+	if old.id == new.id {
+		return 0
+	}
+	var renamed []Triple
+	for k, t := range g.triples {
+		if r, ok := renameBlank(t, old, new); ok {
+			delete(g.triples, k)
+			renamed = append(renamed, r)
+		}
+	}
+	g.Add(renamed...)
+	return len(renamed)
+}
+
+// renameBlank returns the triple with the blank node old replaced by new,
+// and true if there was any to replace.
+func renameBlank(t Triple, old, new Blank) (Triple, bool) {
+	changed := false
+	t = relabelBlanks(t, func(b Blank) Blank {
+		if b.id == old.id {
+			changed = true
+			return new
+		}
+		return b
+	})
+	return t, changed
+}
+
+// relabelBlanks returns the triple with every blank node b, including those
+// inside quoted triples, replaced by f(b).
+func relabelBlanks(t Triple, f func(Blank) Blank) Triple {
+	switch s := t.Subj.(type) {
+	case Blank:
+		t.Subj = f(s)
+	case QuotedTriple:
+		t.Subj = QuotedTriple{relabelBlanks(s.Triple, f)}
+	}
+	switch o := t.Obj.(type) {
+	case Blank:
+		t.Obj = f(o)
+	case QuotedTriple:
+		t.Obj = QuotedTriple{relabelBlanks(o.Triple, f)}
+	}
+	return t
+}
+
+// AllIRIs returns the distinct IRIs referenced by the triples in the graph,
+// in any position, including the datatypes of literals and the terms of
+// quoted triples. The IRIs are returned sorted.
+func AllIRIs(g *Graph) []IRI {
+	seen := make(map[string]bool)
+	var iris []IRI
+	var add func(t Term)
+	add = func(t Term) {
+		var iri IRI
+		switch term := t.(type) {
+		case IRI:
+			iri = term
+		case Literal:
+			iri = term.DataType
+		case QuotedTriple:
+			for _, term := range term.Terms() {
+				add(term)
+			}
+			return
+		default:
+			return
+		}
+		if iri.str == "" || seen[iri.str] {
+			return
+		}
+		seen[iri.str] = true
+		iris = append(iris, iri)
+	}
+	for _, t := range g.triples {
+		for _, term := range t.Terms() {
+			add(term)
+		}
+	}
+	sort.Slice(iris, func(i, j int) bool { return iris[i].str < iris[j].str })
+	return iris
+}
+
+// termMatches reports whether term b is identical to the pattern term a.
+func termMatches(a, b Term) bool {
+	if q, ok := a.(QuotedTriple); ok {
+		return quotedMatches(q, b, termMatches)
+	}
+	return a.Type() == b.Type() && a.Serialize(NTriples) == b.Serialize(NTriples)
+}
+
+// quotedMatches reports whether term b is a quoted triple matching the
+// quoted triple pattern a, comparing its terms with matches. Nil terms in
+// the pattern act as wildcards.
+func quotedMatches(a QuotedTriple, b Term, matches func(a, b Term) bool) bool {
+	q, ok := b.(QuotedTriple)
+	if !ok {
+		return false
+	}
+	return (a.Subj == nil || matches(a.Subj, q.Subj)) &&
+		(a.Pred == nil || matches(a.Pred, q.Pred)) &&
+		(a.Obj == nil || matches(a.Obj, q.Obj))
+}
+
+// termMatchesNormalized is like termMatches, but compares IRIs, including
+// literal datatypes, in their normalized form.
+func termMatchesNormalized(a, b Term) bool {
+	switch a := a.(type) {
+	case IRI:
+		b, ok := b.(IRI)
+		return ok && NormalizeIRI(a) == NormalizeIRI(b)
+	case Literal:
+		b, ok := b.(Literal)
+		return ok && a.str == b.str && a.lang == b.lang &&
+			NormalizeIRI(a.DataType) == NormalizeIRI(b.DataType)
+	case QuotedTriple:
+		return quotedMatches(a, b, termMatchesNormalized)
+	}
+	return termMatches(a, b)
+}
+
+// SyncGraph is a Graph which is safe for concurrent use by multiple goroutines,
+// for example several decoders populating the same graph in parallel.
+//
+// Locking is done on the whole graph: Add, Remove and RenameBlank take an
+// exclusive lock, while Has, Len, Triples, Match and Annotations share a read
+// lock, so readers never block each other. The slices returned by Triples,
+// Match and Annotations are copies, and can be used freely after the lock is
+// released.
+type SyncGraph struct {
+	mu sync.RWMutex
+	g  *Graph
+}
+
+// NewSyncGraph returns a new, empty SyncGraph.
+func NewSyncGraph() *SyncGraph {
+	return &SyncGraph{g: NewGraph()}
+}
+
+// Add adds the given triples to the graph. All the triples are added
+// under a single lock, so adding in batches reduces lock contention.
+func (sg *SyncGraph) Add(ts ...Triple) {
+	sg.mu.Lock()
+	sg.g.Add(ts...)
+	sg.mu.Unlock()
+}
+
+// Remove removes the given triples from the graph.
+func (sg *SyncGraph) Remove(ts ...Triple) {
+	sg.mu.Lock()
+	sg.g.Remove(ts...)
+	sg.mu.Unlock()
+}
+
+// Has returns true if the triple is in the graph.
+func (sg *SyncGraph) Has(t Triple) bool {
+	sg.mu.RLock()
+	defer sg.mu.RUnlock()
+	return sg.g.Has(t)
+}
+
+// Len returns the number of triples in the graph.
+func (sg *SyncGraph) Len() int {
+	sg.mu.RLock()
+	defer sg.mu.RUnlock()
+	return sg.g.Len()
+}
+
+// Triples returns all the triples in the graph, in no particular order.
+func (sg *SyncGraph) Triples() []Triple {
+	sg.mu.RLock()
+	defer sg.mu.RUnlock()
+	return sg.g.Triples()
+}
+
+// ToTriples returns all the triples in the graph, sorted. See Graph.ToTriples.
+func (sg *SyncGraph) ToTriples() []Triple {
+	sg.mu.RLock()
+	defer sg.mu.RUnlock()
+	return sg.g.ToTriples()
+}
+
+// RenameBlank replaces the blank node old with new, returning the number of
+// triples changed. See Graph.RenameBlank.
+func (sg *SyncGraph) RenameBlank(old, new Blank) int {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+	return sg.g.RenameBlank(old, new)
+}
+
+// SubgraphForSubjects returns a new graph with the triples of the given
+// subjects. See Graph.SubgraphForSubjects.
+func (sg *SyncGraph) SubgraphForSubjects(subjects []Subject, followBlanks bool) *Graph {
+	sg.mu.RLock()
+	defer sg.mu.RUnlock()
+	return sg.g.SubgraphForSubjects(subjects, followBlanks)
+}
+
+// Annotations returns the triples annotating the triple t.
+// See Graph.Annotations.
+func (sg *SyncGraph) Annotations(t Triple) []Triple {
+	sg.mu.RLock()
+	defer sg.mu.RUnlock()
+	return sg.g.Annotations(t)
+}
+
+// SetNormalizeIRIs sets whether Match compares IRIs in normalized form.
+// See Graph.NormalizeIRIs.
+func (sg *SyncGraph) SetNormalizeIRIs(on bool) {
+	sg.mu.Lock()
+	sg.g.NormalizeIRIs = on
+	sg.mu.Unlock()
+}
+
+// Match returns all triples in the graph matching the given pattern.
+// See Graph.Match.
+func (sg *SyncGraph) Match(s Subject, p Predicate, o Object) []Triple {
+	sg.mu.RLock()
+	defer sg.mu.RUnlock()
+	return sg.g.Match(s, p, o)
+}
@@ -0,0 +1,335 @@
+package rdf
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func mustParseTriples(t *testing.T, s string) []Triple {
+	ts, err := NewTripleDecoder(bytes.NewBufferString(s), Turtle).DecodeAll()
+	if err != nil {
+		t.Fatalf("parsing %q failed: %v", s, err)
+	}
+	return ts
+}
+
+func TestGraph(t *testing.T) {
+	ts := mustParseTriples(t, `
+@prefix ex: <http://example.org/> .
+ex:a ex:p ex:b, "b", 1 .
+ex:a ex:q ex:c .
+ex:b ex:p ex:c .`)
+
+	g := NewGraph()
+	g.Add(ts...)
+	g.Add(ts[0])
+	if g.Len() != 5 {
+		t.Fatalf("Graph.Len() => %d; want 5", g.Len())
+	}
+
+	a := IRI{str: "http://example.org/a"}
+	p := IRI{str: "http://example.org/p"}
+	c := IRI{str: "http://example.org/c"}
+	matchTests := []struct {
+		s    Subject
+		p    Predicate
+		o    Object
+		want int
+	}{
+		{nil, nil, nil, 5},
+		{a, nil, nil, 4},
+		{a, p, nil, 3},
+		{nil, p, nil, 4},
+		{nil, nil, c, 2},
+		{a, p, c, 0},
+		{nil, nil, Literal{str: "b", DataType: xsdString}, 1},
+		{nil, nil, Literal{str: "1", DataType: xsdInteger}, 1},
+		{nil, nil, Literal{str: "1", DataType: xsdString}, 0},
+	}
+	for _, tt := range matchTests {
+		if got := g.Match(tt.s, tt.p, tt.o); len(got) != tt.want {
+			t.Errorf("Graph.Match(%v, %v, %v) => %d triples; want %d", tt.s, tt.p, tt.o, len(got), tt.want)
+		}
+	}
+
+	if !g.Has(ts[3]) {
+		t.Errorf("Graph.Has(%v) => false; want true", ts[3])
+	}
+	g.Remove(ts[3])
+	if g.Has(ts[3]) || g.Len() != 4 {
+		t.Errorf("after Graph.Remove(%v): Has => %v, Len => %d; want false, 4", ts[3], g.Has(ts[3]), g.Len())
+	}
+	if len(g.Triples()) != 4 {
+		t.Errorf("Graph.Triples() => %d triples; want 4", len(g.Triples()))
+	}
+}
+
+func TestGraphMatchNormalizeIRIs(t *testing.T) {
+	g := NewGraph()
+	g.Add(mustParseTriples(t, `
+@prefix ex: <http://example.org/> .
+<http://example.org/%7Ea> ex:p <http://example.org/a%2fb>, "1"^^<http://example.org/dt%7e> .`)...)
+
+	s := IRI{str: "HTTP://EXAMPLE.org/~a"}
+	o := IRI{str: "http://example.org/a%2Fb"}
+	lit := NewTypedLiteral("1", IRI{str: "http://example.org/dt~"})
+
+	if n := len(g.Match(s, nil, nil)); n != 0 {
+		t.Errorf("exact Match(%v, nil, nil) => %d triples; want 0", s, n)
+	}
+
+	g.NormalizeIRIs = true
+	if n := len(g.Match(s, nil, nil)); n != 2 {
+		t.Errorf("normalized Match(%v, nil, nil) => %d triples; want 2", s, n)
+	}
+	if n := len(g.Match(nil, nil, o)); n != 1 {
+		t.Errorf("normalized Match(nil, nil, %v) => %d triples; want 1", o, n)
+	}
+	if n := len(g.Match(nil, nil, lit)); n != 1 {
+		t.Errorf("normalized Match(nil, nil, %v) => %d triples; want 1", lit, n)
+	}
+}
+
+func TestAllIRIs(t *testing.T) {
+	g := NewGraph()
+	g.Add(mustParseTriples(t, `
+@prefix ex: <http://example.org/> .
+ex:a ex:p ex:b, "b", "c"@en, "1"^^ex:dt, _:x .
+_:x ex:p ex:a .
+ex:a ex:says << ex:q ex:r "2"^^ex:qdt >> .`)...)
+
+	want := []string{
+		"http://example.org/a",
+		"http://example.org/b",
+		"http://example.org/dt",
+		"http://example.org/p",
+		"http://example.org/q",
+		"http://example.org/qdt",
+		"http://example.org/r",
+		"http://example.org/says",
+		"http://www.w3.org/1999/02/22-rdf-syntax-ns#langString",
+		"http://www.w3.org/2001/XMLSchema#string",
+	}
+	got := AllIRIs(g)
+	if len(got) != len(want) {
+		t.Fatalf("AllIRIs => %v; want %v", got, want)
+	}
+	for i, iri := range got {
+		if iri.str != want[i] {
+			t.Errorf("AllIRIs[%d] => %v; want %v", i, iri, want[i])
+		}
+	}
+}
+
+func TestDanglingBlanks(t *testing.T) {
+	g := NewGraph()
+	g.Add(mustParseTriples(t, `
+@prefix ex: <http://example.org/> .
+ex:a ex:p _:described, _:orphan .
+_:described ex:p "x" .
+_:root ex:p ex:a .
+_:loop ex:p _:loop .
+ex:a ex:says << _:quoted ex:p ex:a >> .
+_:quoted ex:p "y" .
+<< ex:a ex:p _:onlyQuoted >> ex:q ex:r .`)...)
+
+	want := []string{"onlyQuoted", "orphan", "root"}
+	got := g.DanglingBlanks()
+	if len(got) != len(want) {
+		t.Fatalf("DanglingBlanks() => %v; want %v", got, want)
+	}
+	for i, b := range got {
+		if b.String() != want[i] {
+			t.Errorf("DanglingBlanks()[%d] => %v; want %v", i, b, want[i])
+		}
+	}
+}
+
+func TestRenameBlank(t *testing.T) {
+	g := NewGraph()
+	g.Add(mustParseTriples(t, `
+@prefix ex: <http://example.org/> .
+ex:a ex:p _:x, _:y .
+_:x ex:p "x" .
+_:x ex:q _:x .
+<< _:x ex:p "x" >> ex:source ex:b .
+_:y ex:p "x" .`)...)
+
+	x, y, z := Blank{id: "_:x"}, Blank{id: "_:y"}, Blank{id: "_:z"}
+	if n := g.RenameBlank(x, z); n != 4 {
+		t.Errorf("RenameBlank(x, z) => %d; want 4", n)
+	}
+	if got := g.Match(x, nil, nil); len(got) != 0 {
+		t.Errorf("after RenameBlank, Match(x, nil, nil) => %v; want none", got)
+	}
+	if got := g.Match(z, nil, nil); len(got) != 2 {
+		t.Errorf("after RenameBlank, Match(z, nil, nil) => %v; want 2 triples", got)
+	}
+	quoted := Triple{Subj: z, Pred: IRI{str: "http://example.org/p"}, Obj: Literal{str: "x", DataType: xsdString}}
+	if got := g.Match(QuotedTriple{quoted}, nil, nil); len(got) != 1 {
+		t.Errorf("after RenameBlank, Match(<< %v >>, nil, nil) => %v; want 1 triple", quoted, got)
+	}
+	if n := g.RenameBlank(Blank{id: "_:missing"}, z); n != 0 {
+		t.Errorf("RenameBlank of missing blank => %d; want 0", n)
+	}
+
+	// Renaming to an existing blank node merges the two.
+	if n := g.RenameBlank(y, z); n != 2 || g.Len() != 4 {
+		t.Errorf("RenameBlank(y, z) => %d, graph length %d; want 2, 4", n, g.Len())
+	}
+}
+
+func TestAnnotations(t *testing.T) {
+	g := NewGraph()
+	g.Add(mustParseTriples(t, `
+@prefix ex: <http://example.org/> .
+ex:alice ex:knows ex:bob {| ex:since 2001 ; ex:source ex:census |} .
+ex:alice ex:knows ex:carol {| ex:since 2010 |} .
+<< ex:dave ex:knows ex:bob >> ex:source ex:rumour .
+ex:erin ex:believes << ex:alice ex:knows ex:bob >> .`)...)
+
+	ex := func(s string) IRI { return IRI{str: "http://example.org/" + s} }
+	knows := func(s, o string) Triple { return Triple{Subj: ex(s), Pred: ex("knows"), Obj: ex(o)} }
+
+	tests := []struct {
+		t    Triple
+		want int
+	}{
+		{knows("alice", "bob"), 2},
+		{knows("alice", "carol"), 1},
+		{knows("dave", "bob"), 1},   // not asserted, but annotated
+		{knows("erin", "alice"), 0}, // not annotated
+	}
+	for _, test := range tests {
+		if got := g.Annotations(test.t); len(got) != test.want {
+			t.Errorf("Annotations(%v) => %v; want %d triples", test.t, got, test.want)
+		}
+	}
+
+	// Quoted triple patterns, with wildcards
+	patterns := []struct {
+		s    Subject
+		o    Object
+		want int
+	}{
+		{QuotedTriple{Triple{Subj: ex("alice")}}, nil, 3},
+		{QuotedTriple{Triple{Obj: ex("bob")}}, nil, 3},
+		{QuotedTriple{Triple{Subj: ex("dave"), Pred: ex("knows")}}, nil, 1},
+		{QuotedTriple{}, nil, 4},
+		{nil, QuotedTriple{Triple{Subj: ex("alice")}}, 1},
+		{ex("alice"), QuotedTriple{}, 0},
+	}
+	for _, test := range patterns {
+		if got := g.Match(test.s, nil, test.o); len(got) != test.want {
+			t.Errorf("Match(%v, nil, %v) => %v; want %d triples", test.s, test.o, got, test.want)
+		}
+	}
+}
+
+func TestGraphToTriples(t *testing.T) {
+	ts := mustParseTriples(t, `
+@prefix ex: <http://example.org/> .
+ex:b ex:p ex:o2, ex:o1 .
+ex:a ex:q "x" ; ex:p _:z .
+_:z ex:p << ex:a ex:p ex:b >> .`)
+	want := []string{
+		"<http://example.org/a> <http://example.org/p> _:z .\n",
+		"<http://example.org/a> <http://example.org/q> \"x\" .\n",
+		"<http://example.org/b> <http://example.org/p> <http://example.org/o1> .\n",
+		"<http://example.org/b> <http://example.org/p> <http://example.org/o2> .\n",
+		"_:z <http://example.org/p> << <http://example.org/a> <http://example.org/p> <http://example.org/b> >> .\n",
+	}
+
+	g1, g2 := NewGraph(), NewGraph()
+	g1.Add(ts...)
+	for i := len(ts) - 1; i >= 0; i-- {
+		g2.Add(ts[i])
+	}
+	for _, g := range []*Graph{g1, g2} {
+		got := g.ToTriples()
+		if len(got) != len(want) {
+			t.Fatalf("ToTriples() => %v; want %d triples", got, len(want))
+		}
+		for i, tr := range got {
+			if tr.Serialize(NTriples) != want[i] {
+				t.Errorf("ToTriples()[%d] => %q; want %q", i, tr.Serialize(NTriples), want[i])
+			}
+		}
+	}
+}
+
+func TestSubgraphForSubjects(t *testing.T) {
+	g := NewGraph()
+	g.Add(mustParseTriples(t, `
+@prefix ex: <http://example.org/> .
+ex:a ex:name "A" ;
+	ex:address [ ex:city "Oslo" ; ex:geo [ ex:lat 59.9 ] ] ;
+	ex:knows ex:b .
+ex:b ex:name "B" .
+ex:c ex:name "C" ;
+	ex:claims << _:x ex:p ex:o >> .
+_:x ex:q "x" .
+_:x ex:r _:x .`)...)
+
+	tests := []struct {
+		subjects     []Subject
+		followBlanks bool
+		want         int
+	}{
+		{[]Subject{IRI{str: "http://example.org/a"}}, false, 3},
+		{[]Subject{IRI{str: "http://example.org/a"}}, true, 6},
+		{[]Subject{IRI{str: "http://example.org/a"}, IRI{str: "http://example.org/b"}}, false, 4},
+		{[]Subject{IRI{str: "http://example.org/c"}}, true, 4}, // blank in quoted triple, and a cycle
+		{[]Subject{IRI{str: "http://example.org/none"}}, true, 0},
+		{nil, true, 0},
+	}
+	for _, test := range tests {
+		sub := g.SubgraphForSubjects(test.subjects, test.followBlanks)
+		if sub.Len() != test.want {
+			t.Errorf("SubgraphForSubjects(%v, %v) => %v; want %d triples", test.subjects, test.followBlanks, sub.Triples(), test.want)
+		}
+		for _, tr := range sub.Triples() {
+			if !g.Has(tr) {
+				t.Errorf("SubgraphForSubjects(%v, %v) => %v; not in graph", test.subjects, test.followBlanks, tr)
+			}
+		}
+		if test.followBlanks && len(sub.DanglingBlanks()) != 0 {
+			t.Errorf("SubgraphForSubjects(%v, true) => dangling blanks %v", test.subjects, sub.DanglingBlanks())
+		}
+	}
+}
+
+func TestSyncGraph(t *testing.T) {
+	g := NewSyncGraph()
+	p := IRI{str: "http://example.org/p"}
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				tr := Triple{
+					Subj: IRI{str: fmt.Sprintf("http://example.org/s%d", i)},
+					Pred: p,
+					Obj:  Literal{str: fmt.Sprintf("%d", w), DataType: xsdString},
+				}
+				g.Add(tr)
+				if !g.Has(tr) {
+					t.Errorf("SyncGraph.Has(%v) => false; want true", tr)
+				}
+				g.Match(tr.Subj, nil, nil)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if g.Len() != 800 {
+		t.Errorf("SyncGraph.Len() => %d; want 800", g.Len())
+	}
+	if got := len(g.Match(nil, p, Literal{str: "3", DataType: xsdString})); got != 100 {
+		t.Errorf("SyncGraph.Match => %d triples; want 100", got)
+	}
+}
@@ -0,0 +1,257 @@
+package rdf
+
+import "strings"
+
+// NormalizeIRI returns the IRI in normal form, according to the syntax-based
+// normalization of RFC 3986, section 6.2.2, so that equivalent IRIs which
+// differ only in the case of the scheme, the host or percent-encoded octets,
+// or in percent-encoding of unreserved characters, are normalized to the same IRI:
+//
+//	HTTP://Example.ORG/%7euser/a%2fb  =>  http://example.org/~user/a%2Fb
+//
+// Dot-segments in the path are left as is.
+func NormalizeIRI(iri IRI) IRI {
+	s := iri.str
+
+	var b strings.Builder
+	b.Grow(len(s))
+
+	// Lowercase the scheme
+	rest := s
+	if i := strings.IndexByte(s, ':'); i > 0 && isScheme(s[:i]) {
+		b.WriteString(strings.ToLower(s[:i+1]))
+		rest = s[i+1:]
+	}
+
+	// Lowercase the host
+	if strings.HasPrefix(rest, "//") {
+		auth := rest[2:]
+		if i := strings.IndexAny(auth, "/?#"); i != -1 {
+			auth = auth[:i]
+		}
+		rest = rest[2+len(auth):]
+		host := auth
+		if i := strings.LastIndexByte(host, '@'); i != -1 {
+			b.WriteString("//")
+			normalizePercent(&b, host[:i+1])
+			host = host[i+1:]
+		} else {
+			b.WriteString("//")
+		}
+		normalizePercent(&b, strings.ToLower(host))
+	}
+
+	normalizePercent(&b, rest)
+	return IRI{str: b.String()}
+}
+
+// ResolveIRI resolves the IRI reference ref against the base IRI, according
+// to RFC 3986, section 5.2. In particular, the empty reference resolves to
+// the base without its fragment, and a fragment-only reference replaces the
+// fragment of the base:
+//
+//	ResolveIRI(<http://example.org/doc#a>, "")      => <http://example.org/doc>
+//	ResolveIRI(<http://example.org/doc#a>, "#b")    => <http://example.org/doc#b>
+//	ResolveIRI(<http://example.org/a/b>, "../c?q")  => <http://example.org/c?q>
+//
+// If ref is absolute, it is returned with its dot-segments removed. If the
+// base is empty, ref is returned as is, since there is nothing to resolve it
+// against.
+func ResolveIRI(base IRI, ref string) IRI {
+	r := splitIRI(ref)
+	if r.scheme == "" && base.str == "" {
+		return IRI{str: ref}
+	}
+	b := splitIRI(base.str)
+
+	var t iriParts
+	switch {
+	case r.scheme != "":
+		t = r
+		t.path = removeDotSegments(r.path)
+	case r.hasAuth:
+		t = r
+		t.scheme = b.scheme
+		t.path = removeDotSegments(r.path)
+	default:
+		t.scheme, t.auth, t.hasAuth = b.scheme, b.auth, b.hasAuth
+		switch {
+		case r.path == "":
+			t.path = b.path
+			t.query, t.hasQuery = b.query, b.hasQuery
+			if r.hasQuery {
+				t.query, t.hasQuery = r.query, true
+			}
+		case r.path[0] == '/':
+			t.path = removeDotSegments(r.path)
+			t.query, t.hasQuery = r.query, r.hasQuery
+		default:
+			// Merge the reference path with the base path
+			var merged string
+			if b.hasAuth && b.path == "" {
+				merged = "/" + r.path
+			} else {
+				merged = b.path[:strings.LastIndexByte(b.path, '/')+1] + r.path
+			}
+			t.path = removeDotSegments(merged)
+			t.query, t.hasQuery = r.query, r.hasQuery
+		}
+		t.frag, t.hasFrag = r.frag, r.hasFrag
+	}
+	return IRI{str: t.String()}
+}
+
+// iriParts are the components of an IRI reference, see RFC 3986, section 3.
+type iriParts struct {
+	scheme   string // empty for a relative reference
+	auth     string
+	path     string
+	query    string
+	frag     string
+	hasAuth  bool
+	hasQuery bool
+	hasFrag  bool
+}
+
+// splitIRI splits the IRI reference s into its components.
+func splitIRI(s string) iriParts {
+	var p iriParts
+	if i := strings.IndexAny(s, ":/?#"); i > 0 && s[i] == ':' && isScheme(s[:i]) {
+		p.scheme = s[:i]
+		s = s[i+1:]
+	}
+	if i := strings.IndexByte(s, '#'); i != -1 {
+		p.frag, p.hasFrag = s[i+1:], true
+		s = s[:i]
+	}
+	if i := strings.IndexByte(s, '?'); i != -1 {
+		p.query, p.hasQuery = s[i+1:], true
+		s = s[:i]
+	}
+	if strings.HasPrefix(s, "//") {
+		s = s[2:]
+		i := strings.IndexByte(s, '/')
+		if i == -1 {
+			i = len(s)
+		}
+		p.auth, p.hasAuth = s[:i], true
+		s = s[i:]
+	}
+	p.path = s
+	return p
+}
+
+// String recomposes the IRI reference from its components.
+func (p iriParts) String() string {
+	var b strings.Builder
+	if p.scheme != "" {
+		b.WriteString(p.scheme)
+		b.WriteByte(':')
+	}
+	if p.hasAuth {
+		b.WriteString("//")
+		b.WriteString(p.auth)
+	}
+	b.WriteString(p.path)
+	if p.hasQuery {
+		b.WriteByte('?')
+		b.WriteString(p.query)
+	}
+	if p.hasFrag {
+		b.WriteByte('#')
+		b.WriteString(p.frag)
+	}
+	return b.String()
+}
+
+// removeDotSegments removes the "." and ".." segments from the path,
+// as described in RFC 3986, section 5.2.4.
+func removeDotSegments(path string) string {
+	var out []string // output segments, each starting with '/' unless first in a relative path
+	for path != "" {
+		switch {
+		case strings.HasPrefix(path, "../"):
+			path = path[3:]
+		case strings.HasPrefix(path, "./"):
+			path = path[2:]
+		case strings.HasPrefix(path, "/./"):
+			path = path[2:]
+		case path == "/.":
+			path = "/"
+		case strings.HasPrefix(path, "/../"):
+			path = path[3:]
+			if len(out) > 0 {
+				out = out[:len(out)-1]
+			}
+		case path == "/..":
+			path = "/"
+			if len(out) > 0 {
+				out = out[:len(out)-1]
+			}
+		case path == "." || path == "..":
+			path = ""
+		default:
+			i := strings.IndexByte(path[1:], '/') + 1
+			if i == 0 {
+				i = len(path)
+			}
+			out = append(out, path[:i])
+			path = path[i:]
+		}
+	}
+	return strings.Join(out, "")
+}
+
+// normalizePercent writes s to b, decoding percent-encoded unreserved
+// characters, and uppercasing the hex digits of other percent-encodings.
+func normalizePercent(b *strings.Builder, s string) {
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2]) {
+			b.WriteByte(s[i])
+			continue
+		}
+		c := unhex(s[i+1])<<4 | unhex(s[i+2])
+		if isUnreserved(c) {
+			b.WriteByte(c)
+		} else {
+			b.WriteByte('%')
+			b.WriteString(strings.ToUpper(s[i+1 : i+3]))
+		}
+		i += 2
+	}
+}
+
+// isScheme returns true if s is a valid IRI scheme.
+func isScheme(s string) bool {
+	for i, r := range s {
+		switch {
+		case isAlpha(r):
+		case i > 0 && (isDigit(r) || r == '+' || r == '-' || r == '.'):
+		default:
+			return false
+		}
+	}
+	return len(s) > 0
+}
+
+// isUnreserved returns true if c is an unreserved character,
+// as defined by RFC 3986.
+func isUnreserved(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func isHex(c byte) bool {
+	return ('0' <= c && c <= '9') || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
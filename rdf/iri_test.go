@@ -0,0 +1,99 @@
+package rdf
+
+import "testing"
+
+func TestNormalizeIRI(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"http://example.org/a", "http://example.org/a"},
+		{"HTTP://Example.ORG/Path", "http://example.org/Path"},
+		{"http://example.org/%7euser", "http://example.org/~user"},
+		{"http://example.org/a%2fb", "http://example.org/a%2Fb"},
+		{"http://example.org/%41%62%2d", "http://example.org/Ab-"},
+		{"http://User@Example.org:8080/", "http://User@example.org:8080/"},
+		{"http://example.org/a?q=%3d#F%c3%a5", "http://example.org/a?q=%3D#F%C3%A5"},
+		{"urn:ISBN:0451", "urn:ISBN:0451"},
+		{"http://example.org/100%", "http://example.org/100%"},
+		{"http://example.org/%zz", "http://example.org/%zz"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeIRI(IRI{str: tt.in}); got.str != tt.want {
+			t.Errorf("NormalizeIRI(%q) => %q; want %q", tt.in, got.str, tt.want)
+		}
+	}
+}
+
+func TestResolveIRI(t *testing.T) {
+	// Examples from RFC 3986, section 5.4.
+	base := IRI{str: "http://a/b/c/d;p?q"}
+	tests := []struct {
+		ref, want string
+	}{
+		{"g:h", "g:h"},
+		{"g", "http://a/b/c/g"},
+		{"./g", "http://a/b/c/g"},
+		{"g/", "http://a/b/c/g/"},
+		{"/g", "http://a/g"},
+		{"//g", "http://g"},
+		{"?y", "http://a/b/c/d;p?y"},
+		{"g?y", "http://a/b/c/g?y"},
+		{"#s", "http://a/b/c/d;p?q#s"},
+		{"g#s", "http://a/b/c/g#s"},
+		{"g?y#s", "http://a/b/c/g?y#s"},
+		{";x", "http://a/b/c/;x"},
+		{"g;x", "http://a/b/c/g;x"},
+		{"g;x?y#s", "http://a/b/c/g;x?y#s"},
+		{"", "http://a/b/c/d;p?q"},
+		{".", "http://a/b/c/"},
+		{"./", "http://a/b/c/"},
+		{"..", "http://a/b/"},
+		{"../", "http://a/b/"},
+		{"../g", "http://a/b/g"},
+		{"../..", "http://a/"},
+		{"../../", "http://a/"},
+		{"../../g", "http://a/g"},
+		{"../../../g", "http://a/g"},
+		{"../../../../g", "http://a/g"},
+		{"/./g", "http://a/g"},
+		{"/../g", "http://a/g"},
+		{"g.", "http://a/b/c/g."},
+		{".g", "http://a/b/c/.g"},
+		{"g..", "http://a/b/c/g.."},
+		{"..g", "http://a/b/c/..g"},
+		{"./../g", "http://a/b/g"},
+		{"./g/.", "http://a/b/c/g/"},
+		{"g/./h", "http://a/b/c/g/h"},
+		{"g/../h", "http://a/b/c/h"},
+		{"g;x=1/./y", "http://a/b/c/g;x=1/y"},
+		{"g;x=1/../y", "http://a/b/c/y"},
+		{"g?y/./x", "http://a/b/c/g?y/./x"},
+		{"g?y/../x", "http://a/b/c/g?y/../x"},
+		{"g#s/./x", "http://a/b/c/g#s/./x"},
+		{"g#s/../x", "http://a/b/c/g#s/../x"},
+		{"http:g", "http:g"},
+	}
+	for _, tt := range tests {
+		if got := ResolveIRI(base, tt.ref); got.str != tt.want {
+			t.Errorf("ResolveIRI(%v, %q) => %q; want %q", base, tt.ref, got.str, tt.want)
+		}
+	}
+
+	// Same-document references
+	doc := IRI{str: "http://example.org/doc#frag"}
+	for ref, want := range map[string]string{
+		"":     "http://example.org/doc",
+		"#foo": "http://example.org/doc#foo",
+		"#":    "http://example.org/doc#",
+	} {
+		if got := ResolveIRI(doc, ref); got.str != want {
+			t.Errorf("ResolveIRI(%v, %q) => %q; want %q", doc, ref, got.str, want)
+		}
+	}
+	if got := ResolveIRI(IRI{str: "http://example.org"}, "a"); got.str != "http://example.org/a" {
+		t.Errorf("ResolveIRI(<http://example.org>, \"a\") => %q; want %q", got.str, "http://example.org/a")
+	}
+	if got := ResolveIRI(IRI{}, "a/../b"); got.str != "a/../b" {
+		t.Errorf("ResolveIRI(<>, \"a/../b\") => %q; want it unchanged", got.str)
+	}
+}
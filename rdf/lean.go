@@ -0,0 +1,185 @@
+package rdf
+
+import "sort"
+
+// Lean returns the lean version of the graph; the smallest subgraph
+// which is an instance of the graph. A graph is not lean when some of its
+// triples are redundant, because the blank nodes they contain can be mapped
+// to other terms in the graph, so that the triples become other triples
+// in the graph. For example, in the graph
+//  <s> <p> _:b .
+//  <s> <p> <o> .
+// the first triple says nothing more than the second, and is removed.
+//
+// Leaning is done by repeatedly looking for a mapping of the blank nodes
+// which maps the graph into itself minus one of its triples, until no such
+// mapping exists. The search is exponential in the worst case, but prunes
+// the candidates by predicate and by the already mapped blank nodes, and
+// maps the most constrained triples first.
+func (g *Graph) Lean() *Graph {
+	lean := NewGraph()
+	for k, t := range g.triples {
+		lean.triples[k] = t
+	}
+
+outer:
+	for {
+		// Only triples with blank nodes can be redundant. They are tried in
+		// a fixed order, to make the result deterministic.
+		var blanks []Triple
+		for _, t := range lean.triples {
+			if hasBlank(t) {
+				blanks = append(blanks, t)
+			}
+		}
+		sort.Slice(blanks, func(i, j int) bool { return tripleKey(blanks[i]) < tripleKey(blanks[j]) })
+
+		for _, t := range blanks {
+			target := newPredIndex(lean, t)
+			m := make(map[string]Term)
+			if findHomomorphism(blanks, target, m) {
+				mapped := NewGraph()
+				for _, t := range lean.triples {
+					mapped.Add(mapBlanks(t, m))
+				}
+				lean = mapped
+				continue outer
+			}
+		}
+		return lean
+	}
+}
+
+// hasBlank returns true if the triple has a blank node as subject or object,
+// including inside quoted triples.
+func hasBlank(t Triple) bool {
+	for _, term := range [2]Term{t.Subj, t.Obj} {
+		switch term := term.(type) {
+		case Blank:
+			return true
+		case QuotedTriple:
+			if hasBlank(term.Triple) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// predIndex indexes triples by predicate.
+type predIndex map[string][]Triple
+
+// newPredIndex returns an index of the triples of the graph, except the given triple.
+func newPredIndex(g *Graph, except Triple) predIndex {
+	exceptKey := tripleKey(except)
+	idx := make(predIndex)
+	for k, t := range g.triples {
+		if k == exceptKey {
+			continue
+		}
+		p := t.Pred.Serialize(NTriples)
+		idx[p] = append(idx[p], t)
+	}
+	return idx
+}
+
+// findHomomorphism searches for a mapping of blank nodes (by label) to terms,
+// extending the given mapping m, which maps all the triples ts to triples in
+// the target index. It returns true if found, with the mapping stored in m.
+func findHomomorphism(ts []Triple, target predIndex, m map[string]Term) bool {
+	if len(ts) == 0 {
+		return true
+	}
+
+	// Pick the triple with the fewest candidates given the current mapping.
+	best, bestCands := -1, []Triple(nil)
+	for i, t := range ts {
+		var cands []Triple
+		for _, c := range target[t.Pred.Serialize(NTriples)] {
+			if termMaps(t.Subj, c.Subj, m) && termMaps(t.Obj, c.Obj, m) {
+				cands = append(cands, c)
+			}
+		}
+		if len(cands) == 0 {
+			return false
+		}
+		if best == -1 || len(cands) < len(bestCands) {
+			best, bestCands = i, cands
+		}
+	}
+
+	t := ts[best]
+	rest := make([]Triple, 0, len(ts)-1)
+	rest = append(rest, ts[:best]...)
+	rest = append(rest, ts[best+1:]...)
+
+	for _, c := range bestCands {
+		var bound []string
+		if bindBlank(t.Subj, c.Subj, m, &bound) && bindBlank(t.Obj, c.Obj, m, &bound) &&
+			findHomomorphism(rest, target, m) {
+			return true
+		}
+		for _, b := range bound {
+			delete(m, b)
+		}
+	}
+	return false
+}
+
+// termMaps returns true if term a can be mapped to term b, given the mapping m.
+// Blank nodes inside quoted triples are mapped too, so a quoted triple maps
+// only to a quoted triple which is its image under the mapping.
+func termMaps(a, b Term, m map[string]Term) bool {
+	switch a := a.(type) {
+	case Blank:
+		if to, ok := m[a.id]; ok {
+			return termMatches(to, b)
+		}
+		return true
+	case QuotedTriple:
+		q, ok := b.(QuotedTriple)
+		return ok && termMatches(a.Pred, q.Pred) && termMaps(a.Subj, q.Subj, m) && termMaps(a.Obj, q.Obj, m)
+	}
+	return termMatches(a, b)
+}
+
+// bindBlank maps term a to b, binding the unmapped blank nodes of a, including
+// those inside quoted triples, and recording their labels in bound. It returns
+// false if a can't be mapped to b.
+func bindBlank(a, b Term, m map[string]Term, bound *[]string) bool {
+	if !termMaps(a, b, m) {
+		return false
+	}
+	switch a := a.(type) {
+	case Blank:
+		if _, ok := m[a.id]; !ok {
+			m[a.id] = b
+			*bound = append(*bound, a.id)
+		}
+	case QuotedTriple:
+		q := b.(QuotedTriple)
+		return bindBlank(a.Subj, q.Subj, m, bound) && bindBlank(a.Obj, q.Obj, m, bound)
+	}
+	return true
+}
+
+// mapBlanks returns the triple with its blank nodes, including those inside
+// quoted triples, replaced according to the mapping.
+func mapBlanks(t Triple, m map[string]Term) Triple {
+	t.Subj = mapBlank(t.Subj, m).(Subject)
+	t.Obj = mapBlank(t.Obj, m).(Object)
+	return t
+}
+
+// mapBlank returns the term with its blank nodes replaced according to the mapping.
+func mapBlank(term Term, m map[string]Term) Term {
+	switch term := term.(type) {
+	case Blank:
+		if to, ok := m[term.id]; ok {
+			return to
+		}
+	case QuotedTriple:
+		return QuotedTriple{mapBlanks(term.Triple, m)}
+	}
+	return term
+}
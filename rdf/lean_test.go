@@ -0,0 +1,77 @@
+package rdf
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestLean(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{
+			`<http://ex/s> <http://ex/p> _:b . <http://ex/s> <http://ex/p> <http://ex/o> .`,
+			`<http://ex/s> <http://ex/p> <http://ex/o> .`,
+		},
+		{
+			`<http://ex/s> <http://ex/p> _:x . <http://ex/s> <http://ex/p> _:y . _:y <http://ex/q> <http://ex/o> .`,
+			`<http://ex/s> <http://ex/p> _:y . _:y <http://ex/q> <http://ex/o> .`,
+		},
+		{
+			// A 2-cycle of blank nodes maps onto a blank node loop.
+			`_:a <http://ex/p> _:b . _:b <http://ex/p> _:a . _:c <http://ex/p> _:c .`,
+			`_:c <http://ex/p> _:c .`,
+		},
+		{
+			// Lean graphs are left as is.
+			`_:a <http://ex/p> _:b . _:b <http://ex/p> _:a . _:a <http://ex/q> "a" .`,
+			`_:a <http://ex/p> _:b . _:b <http://ex/p> _:a . _:a <http://ex/q> "a" .`,
+		},
+		{
+			`<http://ex/s> <http://ex/p> "o" . _:x <http://ex/p> "o" . _:x <http://ex/q> _:y .`,
+			`<http://ex/s> <http://ex/p> "o" . _:x <http://ex/p> "o" . _:x <http://ex/q> _:y .`,
+		},
+		{
+			// The same blank node must be mapped consistently.
+			`_:x <http://ex/p> _:x . <http://ex/a> <http://ex/p> <http://ex/b> .`,
+			`_:x <http://ex/p> _:x . <http://ex/a> <http://ex/p> <http://ex/b> .`,
+		},
+		{
+			// Blank nodes inside quoted triples are mapped too: _:b can't be
+			// mapped to <a>, as << <a> <p> <o> >> is not in the graph.
+			`<< _:b <http://ex/p> <http://ex/o> >> <http://ex/q> <http://ex/r> . _:b <http://ex/z> <http://ex/w> . <http://ex/a> <http://ex/z> <http://ex/w> .`,
+			`<< _:b <http://ex/p> <http://ex/o> >> <http://ex/q> <http://ex/r> . _:b <http://ex/z> <http://ex/w> . <http://ex/a> <http://ex/z> <http://ex/w> .`,
+		},
+		{
+			`<< _:b <http://ex/p> <http://ex/o> >> <http://ex/q> <http://ex/r> . _:b <http://ex/z> <http://ex/w> . << <http://ex/a> <http://ex/p> <http://ex/o> >> <http://ex/q> <http://ex/r> . <http://ex/a> <http://ex/z> <http://ex/w> .`,
+			`<< <http://ex/a> <http://ex/p> <http://ex/o> >> <http://ex/q> <http://ex/r> . <http://ex/a> <http://ex/z> <http://ex/w> .`,
+		},
+	}
+
+	for _, test := range tests {
+		g := NewGraph()
+		g.Add(mustParseTriples(t, test.input)...)
+		want := NewGraph()
+		want.Add(mustParseTriples(t, test.want)...)
+
+		lean := g.Lean()
+		if got, exp := graphString(lean), graphString(want); got != exp {
+			t.Errorf("Lean(%s) =>\n%s\nwant:\n%s", test.input, got, exp)
+		}
+	}
+}
+
+// graphString returns the triples of the graph as sorted N-Triples.
+func graphString(g *Graph) string {
+	var lines []string
+	for _, t := range g.Triples() {
+		lines = append(lines, t.Serialize(NTriples))
+	}
+	sort.Strings(lines)
+	var s string
+	for _, l := range lines {
+		s += l
+	}
+	return s
+}
@@ -12,9 +12,11 @@ type tokenType int
 
 const (
 	// special tokens
-	tokenEOF   tokenType = iota // end of input
-	tokenEOL                    // end of line
-	tokenError                  // an illegal token
+	tokenEOF       tokenType = iota // end of input
+	tokenEOL                        // end of line
+	tokenError                      // an illegal token
+	tokenErrorEOF                   // an illegal token, cut short by the end of input
+	tokenErrorRead                  // the input could not be read
 
 	// turtle tokens
 	tokenIRIAbs            // RDF IRI reference (absolute)
@@ -44,6 +46,10 @@ const (
 	tokenPropertyListEnd   // ']'
 	tokenCollectionStart   // '('
 	tokenCollectionEnd     // ')'
+	tokenQuotedTripleStart // '<<'
+	tokenQuotedTripleEnd   // '>>'
+	tokenAnnotationStart   // '{|'
+	tokenAnnotationEnd     // '|}'
 )
 
 const eof = -1
@@ -76,21 +82,29 @@ type stateFn func(*lexer) stateFn
 type lexer struct {
 	rdr *bufio.Reader
 
-	input    []byte     // the input being scanned (should not inlcude newlines)
-	lineMode bool       // true when lexing line-based formats (N-Triples & N-Quads)
-	unEsc    bool       // true when current token needs to be unescaped
-	state    stateFn    // the next lexing function to enter
-	line     int        // the current line number
-	pos      int        // the current position in input
-	width    int        // width of the last rune read from input
-	start    int        // start of current token
-	tokens   chan token // channel of scanned tokens
+	input    []byte        // the input being scanned (should not inlcude newlines)
+	lineMode bool          // true when lexing line-based formats (N-Triples & N-Quads)
+	lastLine bool          // true when input is the last line, and there is no more to read
+	unEsc    bool          // true when current token needs to be unescaped
+	state    stateFn       // the next lexing function to enter
+	line     int           // the current line number
+	pos      int           // the current position in input
+	width    int           // width of the last rune read from input
+	start    int           // start of current token
+	tokens   chan token    // channel of scanned tokens
+	done     chan struct{} // closed when the consumer stops reading tokens
+	stopped  bool          // true when done is closed (only accessed by the consumer)
 }
 
+// lexerStopped is raised (as a panic) to unwind the lexer goroutine when
+// the consumer stops reading tokens.
+type lexerStopped struct{}
+
 func newLexer(r io.Reader) *lexer {
 	l := lexer{
 		rdr:    bufio.NewReader(r),
 		tokens: make(chan token),
+		done:   make(chan struct{}),
 	}
 	go l.run()
 	return &l
@@ -100,6 +114,7 @@ func newLineLexer(r io.Reader) *lexer {
 	l := lexer{
 		rdr:      bufio.NewReader(r),
 		tokens:   make(chan token),
+		done:     make(chan struct{}),
 		lineMode: true,
 	}
 	go l.run()
@@ -220,12 +235,12 @@ func (l *lexer) emit(typ tokenType) {
 		l.start = l.pos
 		return
 	}
-	l.tokens <- token{
+	l.send(token{
 		typ:  typ,
 		line: l.line,
 		col:  l.start,
 		text: l.unescape(string(l.input[l.start:l.pos]), typ),
-	}
+	})
 
 	l.start = l.pos
 }
@@ -295,13 +310,44 @@ func (l *lexer) nextToken() token {
 	return tok
 }
 
+// send passes the token to the consumer, or terminates the lexer
+// if the consumer has stopped reading.
+func (l *lexer) send(tok token) {
+	select {
+	case l.tokens <- tok:
+	case <-l.done:
+		panic(lexerStopped{})
+	}
+}
+
+// stop makes the lexer terminate, without lexing the rest of the input.
+// No tokens can be read afterwards. Stopping a stopped lexer has no effect.
+func (l *lexer) stop() {
+	if !l.stopped {
+		l.stopped = true
+		close(l.done)
+	}
+}
+
 func (l *lexer) feed(overwrite bool) bool {
 again:
 	line, err := l.rdr.ReadBytes('\n')
+	if err != nil && err != io.EOF {
+		// Not the end of input, but a failing reader, e.g. one
+		// transcoding from another encoding, given invalid input.
+		l.lastLine = true
+		l.send(token{typ: tokenErrorRead, line: l.line + 1, text: err.Error()})
+		return false
+	}
 	if err != nil && len(line) == 0 {
+		l.lastLine = true
 		return false
 	}
+	l.lastLine = err != nil
 
+	if l.line == 0 {
+		line = bytes.TrimPrefix(line, []byte("\xef\xbb\xbf")) // UTF-8 BOM
+	}
 	l.line++
 	if len(line) == 0 || line[0] == '#' {
 		// skip empty lines and lines starting with comment
@@ -323,6 +369,14 @@ again:
 
 // run runs the state machine for the lexer.
 func (l *lexer) run() {
+	defer func() {
+		if e := recover(); e != nil {
+			if _, ok := e.(lexerStopped); !ok {
+				panic(e)
+			}
+		}
+	}()
+
 	for {
 		if !l.feed(false) {
 			break
@@ -335,6 +389,7 @@ func (l *lexer) run() {
 
 	// No more input to lex, emit final EOF token and terminate.
 	// The value of the closed tokens channel is tokenEOF.
+	l.send(token{typ: tokenEOF, line: l.line, col: l.pos})
 	close(l.tokens)
 }
 
@@ -343,12 +398,45 @@ func (l *lexer) run() {
 // errorf returns an error token and terminates the scan by passing
 // back a nil pointer that will be the next state, terminating l.nextToken.
 func (l *lexer) errorf(format string, args ...interface{}) stateFn {
-	l.tokens <- token{
+	l.send(token{
 		tokenError,
 		l.line,
 		l.pos,
 		fmt.Sprintf(format, args...),
+	})
+	return nil
+}
+
+// errorfEOF is like errorf, but should be used when the end of the line is
+// reached in the middle of a token. If it is also the end of input, the error
+// token is marked as caused by an unexpected EOF (i.e. truncated input).
+func (l *lexer) errorfEOF(format string, args ...interface{}) stateFn {
+	typ := tokenError
+	if l.lastLine {
+		typ = tokenErrorEOF
 	}
+	l.send(token{
+		typ,
+		l.line,
+		l.pos,
+		fmt.Sprintf(format, args...),
+	})
+	return nil
+}
+
+// unterminated returns an error token for a string literal with no closing
+// quote(s), reported at the start of the literal, where the mistake usually is,
+// rather than where the lexer gave up.
+func (l *lexer) unterminated(line, col int, long bool, quote rune) stateFn {
+	typ := tokenError
+	if l.lastLine && l.pos >= len(l.input) {
+		typ = tokenErrorEOF
+	}
+	msg := fmt.Sprintf("unterminated string literal: no closing quote: %q", quote)
+	if long {
+		msg = fmt.Sprintf("unterminated long string literal: no closing %c%c%c", quote, quote, quote)
+	}
+	l.send(token{typ, line, col, msg})
 	return nil
 }
 
@@ -377,8 +465,38 @@ func lexAny(l *lexer) stateFn {
 		//l.ignore()
 		return lexBNode
 	case '<':
+		if l.peek() == '<' {
+			l.next()
+			l.ignore()
+			l.emit(tokenQuotedTripleStart)
+			return lexAny
+		}
 		l.ignore()
 		return lexIRI
+	case '>':
+		if l.peek() != '>' {
+			return l.errorf("unexpected character: %q", r)
+		}
+		l.next()
+		l.ignore()
+		l.emit(tokenQuotedTripleEnd)
+		return lexAny
+	case '{':
+		if l.peek() != '|' {
+			return l.errorf("unexpected character: %q", r)
+		}
+		l.next()
+		l.ignore()
+		l.emit(tokenAnnotationStart)
+		return lexAny
+	case '|':
+		if l.peek() != '}' {
+			return l.errorf("unexpected character: %q", r)
+		}
+		l.next()
+		l.ignore()
+		l.emit(tokenAnnotationEnd)
+		return lexAny
 	case 'a':
 		p := l.peek()
 		for _, a := range okAfterRDFType {
@@ -534,17 +652,21 @@ func hasValidScheme(l *lexer) bool {
 	return true
 }
 
-func _lexIRI(l *lexer) (stateFn, bool) {
+// _lexIRI consumes an IRI up until the closing '>', and reports whether it's
+// absolute, or false if it failed to lex the IRI (an error token is then emitted).
+func _lexIRI(l *lexer) (absolute bool, ok bool) {
 	hasScheme := false    // does it have a scheme? defines if IRI is absolute or relative
 	maybeAbsolute := true // false if we reach a non-valid scheme rune before ':'
 	for {
 		r := l.next()
 		if r == eof {
-			return l.errorf("bad IRI: no closing '>'"), false
+			l.errorfEOF("bad IRI: no closing '>'")
+			return false, false
 		}
 		for _, bad := range badIRIRunes {
 			if r == bad {
-				return l.errorf("bad IRI: disallowed character %q", r), false
+				l.errorf("bad IRI: disallowed character %q", r)
+				return false, false
 			}
 		}
 
@@ -555,36 +677,42 @@ func _lexIRI(l *lexer) (stateFn, bool) {
 			case 'u':
 				l.next() // cosume 'u'
 				if !l.acceptRunMin(hex, 4) {
-					return l.errorf("bad IRI: insufficent hex digits in unicode escape"), false
+					l.errorf("bad IRI: insufficent hex digits in unicode escape")
+					return false, false
 				}
 				// Ensure that escaped character is not in badIRIRunes.
 				// We can ignore the error, because we know it's a correctly lexed hex value.
 				i, _ := strconv.ParseInt(string(l.input[l.pos-4:l.pos]), 16, 0)
 				for _, bad := range badIRIRunesEsc {
 					if rune(i) == bad {
-						return l.errorf("bad IRI: disallowed character in unicode escape: %q", string(l.input[l.pos-6:l.pos])), false
+						l.errorf("bad IRI: disallowed character in unicode escape: %q", string(l.input[l.pos-6:l.pos]))
+						return false, false
 					}
 				}
 				l.unEsc = true
 			case 'U':
 				l.next() // cosume 'U'
 				if !l.acceptRunMin(hex, 8) {
-					return l.errorf("bad IRI: insufficent hex digits in unicode escape"), false
+					l.errorf("bad IRI: insufficent hex digits in unicode escape")
+					return false, false
 				}
 				// Ensure that escaped character is not in badIRIRunes.
 				// We can ignore the error, because we know it's a correctly lexed hex value.
 				i, _ := strconv.ParseInt(string(l.input[l.pos-4:l.pos]), 16, 0)
 				for _, bad := range badIRIRunesEsc {
 					if rune(i) == bad {
-						return l.errorf("bad IRI: disallowed character in unicode escape: %q", string(l.input[l.pos-9:l.pos])), false
+						l.errorf("bad IRI: disallowed character in unicode escape: %q", string(l.input[l.pos-9:l.pos]))
+						return false, false
 					}
 				}
 
 				l.unEsc = true
 			case eof:
-				return l.errorf("bad IRI: no closing '>'"), false
+				l.errorfEOF("bad IRI: no closing '>'")
+				return false, false
 			default:
-				return l.errorf("bad IRI: disallowed escape character %q", esc), false
+				l.errorf("bad IRI: disallowed escape character %q", esc)
+				return false, false
 			}
 		}
 		if maybeAbsolute && r == ':' {
@@ -600,13 +728,13 @@ func _lexIRI(l *lexer) (stateFn, bool) {
 		}
 	}
 	l.backup()
-	return nil, hasScheme
+	return hasScheme, true
 }
 
 func lexIRI(l *lexer) stateFn {
-	res, absolute := _lexIRI(l)
-	if res != nil {
-		return res
+	absolute, ok := _lexIRI(l)
+	if !ok {
+		return nil
 	}
 	if absolute {
 		l.emit(tokenIRIAbs)
@@ -622,47 +750,48 @@ func lexIRI(l *lexer) stateFn {
 }
 
 func lexLiteral(l *lexer) stateFn {
+	line, col := l.line, l.start // start of literal, for error reporting
 	quote := l.next()
 	quoteCount := 1
 	var r rune
 
 	l.ignore()
-	for quoteCount < 6 {
-		r = l.next()
-		if r != quote {
-			break
+	if l.peek() == quote {
+		l.next()
+		if l.peek() != quote {
+			// Empty single-quoted string
+			l.ignore()
+			quoteCount = 0
+			goto done
 		}
+		// Triple-quoted string; any quotes following the opening three
+		// are part of the string.
+		l.next()
 		l.ignore()
-		quoteCount++
-	}
-	if quoteCount == 6 {
-		// Empty triple-quoted string
-		l.pos = l.start
-		goto done
-	}
-	if quoteCount == 2 {
-		// Empty single-quoted string
-		quoteCount = 0
-		l.pos = l.start
-		goto done
+		quoteCount = 3
 	}
+	r = l.next()
 outer:
 	for {
 		switch r {
 		case '\n':
 			if quoteCount != 3 {
-				return l.errorf("bad literal: newline not allowed in single-quoted string")
+				// Single-quoted strings cannot span lines.
+				return l.unterminated(line, col, false, quote)
 			}
 			// triple-quoted strings can contain newlines
 			if !l.feed(true) {
-				return l.errorf("bad literal: no closing quote: %q", quote)
+				return l.unterminated(line, col, true, quote)
 			}
 		case '\r':
 			if quoteCount != 3 {
+				if p := l.peek(); p == '\n' || p == eof {
+					return l.unterminated(line, col, false, quote)
+				}
 				return l.errorf("bad literal: carriage return not allowed in single-quoted string")
 			}
 		case eof:
-			return l.errorf("bad literal: no closing quote: %q", quote)
+			return l.unterminated(line, col, quoteCount == 3, quote)
 		case '\\':
 			// handle numeric escape sequences for unicode points:
 			esc := l.next()
@@ -680,7 +809,7 @@ outer:
 				}
 				l.unEsc = true
 			case eof:
-				return l.errorf("bad literal: no closing quote %q", quote)
+				return l.unterminated(line, col, quoteCount == 3, quote)
 			default:
 				return l.errorf("bad literal: disallowed escape character %q", esc)
 			}
@@ -703,16 +832,14 @@ outer:
 		r = l.next()
 	}
 done:
-	if quoteCount == 3 || quoteCount == 6 {
+	if quoteCount == 3 {
 		l.emit(tokenLiteral3)
 	} else {
 		l.emit(tokenLiteral)
 	}
 
 	// ignore quote(s)
-	if quoteCount != 6 {
-		l.pos += quoteCount
-	}
+	l.pos += quoteCount
 	l.ignore()
 
 	// check if literal has language tag or datatype IRI:
@@ -791,7 +918,7 @@ func lexNumber(l *lexer) stateFn {
 					}
 				}
 			default:
-				if r == ' ' || r == ',' || r == ';' || r == eof || r == ')' || r == ']' {
+				if r == ' ' || r == ',' || r == ';' || r == eof || r == ')' || r == ']' || r == '>' || r == '|' {
 					l.backup()
 					break outer
 				}
@@ -816,7 +943,7 @@ func lexNumber(l *lexer) stateFn {
 func lexBNode(l *lexer) stateFn {
 	r := l.next()
 	if r == eof {
-		return l.errorf("bad blank node: unexpected end of line")
+		return l.errorfEOF("bad blank node: unexpected end of line")
 	}
 	if !(isPnCharsU(r) || isDigit(r)) {
 		return l.errorf("bad blank node: invalid character %q", r)
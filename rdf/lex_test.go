@@ -9,6 +9,7 @@ import (
 // Make the token types prettyprint.
 var tokenName = map[tokenType]string{
 	tokenError:             "Error",
+	tokenErrorRead:         "Read error",
 	tokenEOL:               "EOL",
 	tokenEOF:               "EOF",
 	tokenIRIAbs:            "IRI (absolute)",
@@ -38,6 +39,10 @@ var tokenName = map[tokenType]string{
 	tokenPropertyListEnd:   "Property list end",
 	tokenCollectionStart:   "Collection start",
 	tokenCollectionEnd:     "Collection end",
+	tokenQuotedTripleStart: "Quoted triple start",
+	tokenQuotedTripleEnd:   "Quoted triple end",
+	tokenAnnotationStart:   "Annotation start",
+	tokenAnnotationEnd:     "Annotation end",
 }
 
 func (t tokenType) String() string {
@@ -155,6 +160,12 @@ func TestTokens(t *testing.T) {
 			{tokenLiteral3, "a"},
 			{tokenEOF, ""}},
 		},
+		{`""""a""" '''''b''' """""\""""`, []testToken{
+			{tokenLiteral3, `"a`},
+			{tokenLiteral3, "''b"},
+			{tokenLiteral3, `"""`},
+			{tokenEOF, ""}},
+		},
 		{`'''xyz'''`, []testToken{
 			{tokenLiteral3, "xyz"},
 			{tokenEOF, ""}},
@@ -365,6 +376,24 @@ two
 		{`0.99a`, []testToken{
 			{tokenError, "bad literal: illegal number syntax (number followed by 'a')"}},
 		},
+		{`<< <s> :p 1>> :q "x" {| :r _:b |} .`, []testToken{
+			{tokenQuotedTripleStart, ""},
+			{tokenIRIRel, "s"},
+			{tokenPrefixLabel, ":"},
+			{tokenIRISuffix, "p"},
+			{tokenLiteralInteger, "1"},
+			{tokenQuotedTripleEnd, ""},
+			{tokenPrefixLabel, ":"},
+			{tokenIRISuffix, "q"},
+			{tokenLiteral, "x"},
+			{tokenAnnotationStart, ""},
+			{tokenPrefixLabel, ":"},
+			{tokenIRISuffix, "r"},
+			{tokenBNode, "_:b"},
+			{tokenAnnotationEnd, ""},
+			{tokenDot, ""},
+			{tokenEOF, ""}},
+		},
 		{"<s> <p> 1, 2, 3.", []testToken{
 			{tokenIRIRel, "s"},
 			{tokenIRIRel, "p"},
@@ -0,0 +1,81 @@
+package rdf
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// listN is a counter used to generate blank node labels for list nodes,
+// unique among the lists built by NewList.
+var listN uint64
+
+// NewList builds a RDF collection (a rdf:List) of the given items. It returns
+// the head of the list, and the rdf:first/rdf:rest triples linking the list
+// nodes together, which must be added to the graph along with any triple
+// referring to the head. An empty list is represented by rdf:nil, in which
+// case no triples are returned.
+//
+// The list nodes are blank nodes given by newBlank, which must return a new
+// blank node on every call. If newBlank is nil, the nodes are labelled
+// _:list1, _:list2 and so on, unique among the lists built by NewList in this
+// process. Those labels are valid in any document, though, so when the list
+// is added to a graph holding decoded triples, pass a newBlank which avoids
+// the labels already in the graph, or the nodes may be merged.
+func NewList(items []Object, newBlank func() Blank) (head Subject, triples []Triple) {
+	if len(items) == 0 {
+		return RDFNil, nil
+	}
+	if newBlank == nil {
+		newBlank = func() Blank { return Blank{id: fmt.Sprintf("_:list%d", atomic.AddUint64(&listN, 1))} }
+	}
+	nodes := make([]Blank, len(items))
+	for i := range nodes {
+		nodes[i] = newBlank()
+	}
+	triples = make([]Triple, 0, 2*len(items))
+	for i, item := range items {
+		triples = append(triples, Triple{Subj: nodes[i], Pred: rdfFirst, Obj: item})
+		if i == len(items)-1 {
+			triples = append(triples, Triple{Subj: nodes[i], Pred: rdfRest, Obj: RDFNil})
+		} else {
+			triples = append(triples, Triple{Subj: nodes[i], Pred: rdfRest, Obj: nodes[i+1]})
+		}
+	}
+	return nodes[0], triples
+}
+
+// ErrMalformedList is wrapped by the error returned from ExpandList when the
+// triples of the list don't form a proper rdf:List.
+var ErrMalformedList = errors.New("malformed rdf:List")
+
+// ExpandList returns the members of the rdf:List starting at head in the
+// graph g, following the rdf:first/rdf:rest triples until rdf:nil. It is the
+// inverse of NewList; the empty list, rdf:nil, gives an empty slice.
+//
+// The error wraps ErrMalformedList if a list node has no, or more than one,
+// rdf:first or rdf:rest, or if the list is cyclic.
+func ExpandList(g *Graph, head Term) ([]Object, error) {
+	items := []Object{}
+	seen := make(map[string]bool)
+	for node := head; node != Term(RDFNil); {
+		subj, ok := node.(Subject)
+		if !ok || node.Type() == TermQuotedTriple {
+			return nil, fmt.Errorf("%w: list node %v is not an IRI or blank node", ErrMalformedList, node)
+		}
+		k := subj.Serialize(NTriples)
+		if seen[k] {
+			return nil, fmt.Errorf("%w: cycle at list node %s", ErrMalformedList, k)
+		}
+		seen[k] = true
+
+		first := g.Match(subj, rdfFirst, nil)
+		rest := g.Match(subj, rdfRest, nil)
+		if len(first) != 1 || len(rest) != 1 {
+			return nil, fmt.Errorf("%w: list node %s has %d rdf:first and %d rdf:rest; want 1 of each", ErrMalformedList, k, len(first), len(rest))
+		}
+		items = append(items, first[0].Obj)
+		node = rest[0].Obj
+	}
+	return items, nil
+}
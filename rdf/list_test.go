@@ -0,0 +1,116 @@
+package rdf
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestNewList(t *testing.T) {
+	head, ts := NewList(nil, nil)
+	if !TermsEqual(head, RDFNil) || len(ts) != 0 {
+		t.Errorf("NewList(nil, nil) => %v, %v; want rdf:nil and no triples", head, ts)
+	}
+
+	items := []Object{
+		IRI{str: "http://example.org/a"},
+		Literal{str: "b", DataType: xsdString},
+		Blank{id: "_:c"},
+	}
+	head, ts = NewList(items, nil)
+	if len(ts) != 6 {
+		t.Fatalf("NewList(%v, nil) => %d triples; want 6", items, len(ts))
+	}
+
+	// Follow the list from the head, and check the items.
+	g := NewGraph()
+	g.Add(ts...)
+	node := head
+	for i, item := range items {
+		first := g.Match(node, rdfFirst, nil)
+		rest := g.Match(node, rdfRest, nil)
+		if len(first) != 1 || len(rest) != 1 {
+			t.Fatalf("list node %v has %d rdf:first and %d rdf:rest; want 1 of each", node, len(first), len(rest))
+		}
+		if !TermsEqual(first[0].Obj, item) {
+			t.Errorf("list item %d => %v; want %v", i, first[0].Obj, item)
+		}
+		node, _ = rest[0].Obj.(Subject)
+	}
+	if !TermsEqual(node, RDFNil) {
+		t.Errorf("list terminated by %v; want rdf:nil", node)
+	}
+
+	// Separate lists must not share nodes.
+	head2, _ := NewList(items, nil)
+	if TermsEqual(head, head2) {
+		t.Errorf("NewList returned the same head node twice: %v", head)
+	}
+
+	// The list nodes are given by newBlank, if any.
+	n := 0
+	newBlank := func() Blank {
+		n++
+		return Blank{id: fmt.Sprintf("_:node%d", n)}
+	}
+	head, ts = NewList(items, newBlank)
+	if head.Serialize(NTriples) != "_:node1" || len(ts) != 6 || ts[5].Subj.Serialize(NTriples) != "_:node3" {
+		t.Errorf("NewList(%v, newBlank) => %v, %v; want nodes _:node1 to _:node3", items, head, ts)
+	}
+}
+
+func TestExpandList(t *testing.T) {
+	items := []Object{
+		IRI{str: "http://example.org/a"},
+		Literal{str: "b", DataType: xsdString},
+		RDFNil, // a list may contain the empty list
+	}
+	head, ts := NewList(items, nil)
+	g := NewGraph()
+	g.Add(ts...)
+	got, err := ExpandList(g, head)
+	if err != nil {
+		t.Fatalf("ExpandList(NewList(%v)) failed: %v", items, err)
+	}
+	if len(got) != len(items) {
+		t.Fatalf("ExpandList(NewList(%v)) => %v", items, got)
+	}
+	for i := range items {
+		if !TermsEqual(got[i], items[i]) {
+			t.Errorf("ExpandList(NewList(%v))[%d] => %v; want %v", items, i, got[i], items[i])
+		}
+	}
+
+	if got, err := ExpandList(g, RDFNil); err != nil || got == nil || len(got) != 0 {
+		t.Errorf("ExpandList(rdf:nil) => %#v, %v; want empty slice", got, err)
+	}
+
+	// A list parsed from Turtle
+	g = NewGraph()
+	g.Add(mustParseTriples(t, `@prefix ex: <http://example.org/> .
+ex:s ex:p ( 1 2 3 ) .`)...)
+	list := g.Match(IRI{str: "http://example.org/s"}, nil, nil)[0].Obj
+	if got, err := ExpandList(g, list); err != nil || len(got) != 3 {
+		t.Errorf("ExpandList(%v) => %v, %v; want 3 items", list, got, err)
+	}
+
+	malformed := []string{
+		`_:l rdf:first 1 ; rdf:rest _:l .`,                                  // cycle
+		`_:l rdf:first 1 ; rdf:rest _:m . _:m rdf:first 2 ; rdf:rest _:l .`, // longer cycle
+		`_:l rdf:first 1 .`,                       // missing rest
+		`_:l rdf:rest rdf:nil .`,                  // missing first
+		`_:l rdf:first 1, 2 ; rdf:rest rdf:nil .`, // two firsts
+		`_:l rdf:first 1 ; rdf:rest rdf:nil, _:m . _:m rdf:first 2 ; rdf:rest rdf:nil .`, // branching
+		`_:l rdf:first 1 ; rdf:rest "not a node" .`,                                      // literal as list node
+	}
+	for _, input := range malformed {
+		g := NewGraph()
+		g.Add(mustParseTriples(t, "@prefix rdf: <http://www.w3.org/1999/02/22-rdf-syntax-ns#> .\n"+input)...)
+		if got, err := ExpandList(g, Blank{id: "_:l"}); !errors.Is(err, ErrMalformedList) {
+			t.Errorf("ExpandList(%s) => %v, %v; want ErrMalformedList", input, got, err)
+		}
+	}
+	if _, err := ExpandList(NewGraph(), nil); !errors.Is(err, ErrMalformedList) {
+		t.Errorf("ExpandList(nil) => %v; want ErrMalformedList", err)
+	}
+}
@@ -0,0 +1,93 @@
+package rdf
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"sort"
+	"strings"
+)
+
+// mediaTypes maps the media types of the supported formats to the format.
+var mediaTypes = map[string]Format{
+	"application/n-triples": NTriples,
+	"text/turtle":           Turtle,
+	"application/x-turtle":  Turtle,
+	"application/rdf+xml":   RDFXML,
+	"application/n-quads":   NQuads,
+	"text/x-nquads":         NQuads,
+}
+
+// unsupportedMediaTypes are RDF media types recognized, but not supported,
+// by this package.
+var unsupportedMediaTypes = map[string]string{
+	"application/trig":    "TriG",
+	"application/ld+json": "JSON-LD",
+}
+
+// FormatFromMediaType returns the Format of the given media type, as found in
+// a Content-Type header. Parameters are ignored, apart from the charset, which
+// must be UTF-8 (or its subset US-ASCII) if given. For example:
+//
+//	FormatFromMediaType("application/n-triples; charset=utf-8") => NTriples
+//
+// The error lists the supported media types when the media type is not one of
+// them.
+func FormatFromMediaType(contentType string) (Format, error) {
+	mt, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return 0, fmt.Errorf("invalid media type %q: %v", contentType, err)
+	}
+	f, ok := mediaTypes[mt]
+	if !ok {
+		if name, ok := unsupportedMediaTypes[mt]; ok {
+			return 0, fmt.Errorf("media type %q: %s is not supported; supported media types are: %s", mt, name, supportedMediaTypes())
+		}
+		return 0, fmt.Errorf("unrecognized media type %q; supported media types are: %s", mt, supportedMediaTypes())
+	}
+	if cs, ok := params["charset"]; ok {
+		switch strings.ToLower(cs) {
+		case "utf-8", "utf8", "us-ascii":
+		default:
+			return 0, fmt.Errorf("media type %q: unsupported charset %q; only UTF-8 is supported", mt, cs)
+		}
+	}
+	return f, nil
+}
+
+// supportedMediaTypes returns a sorted, comma separated list of the
+// supported media types.
+func supportedMediaTypes() string {
+	mts := make([]string, 0, len(mediaTypes))
+	for mt := range mediaTypes {
+		mts = append(mts, mt)
+	}
+	sort.Strings(mts)
+	return strings.Join(mts, ", ")
+}
+
+// DecodeResponse decodes all the statements from r, in the format given by the
+// contentType media type; see FormatFromMediaType. It is meant for decoding
+// the body of an HTTP response according to its Content-Type header.
+//
+// The statements are returned as quads. Triples, from formats without named
+// graphs, are placed in the default graph, with the same context as the
+// QuadDecoder uses for it.
+func DecodeResponse(contentType string, r io.Reader) ([]Quad, error) {
+	f, err := FormatFromMediaType(contentType)
+	if err != nil {
+		return nil, err
+	}
+	if f == NQuads {
+		return NewQuadDecoder(r, f).DecodeAll()
+	}
+	ts, err := NewTripleDecoder(r, f).DecodeAll()
+	if err != nil {
+		return nil, err
+	}
+	qs := make([]Quad, len(ts))
+	for i, t := range ts {
+		qs[i] = Quad{Triple: t, Ctx: defaultGraph}
+	}
+	return qs, nil
+}
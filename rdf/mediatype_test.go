@@ -0,0 +1,57 @@
+package rdf
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatFromMediaType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        Format
+		errContains string
+	}{
+		{"application/n-triples", NTriples, ""},
+		{"application/n-triples; charset=utf-8", NTriples, ""},
+		{"Text/Turtle;charset=UTF-8", Turtle, ""},
+		{"application/rdf+xml", RDFXML, ""},
+		{"application/n-quads; charset=\"utf-8\"", NQuads, ""},
+		{"application/n-triples; charset=iso-8859-1", 0, "unsupported charset"},
+		{"application/trig", 0, "TriG is not supported"},
+		{"application/json", 0, "supported media types are: application/n-quads, application/n-triples"},
+		{"", 0, "invalid media type"},
+	}
+	for _, test := range tests {
+		got, err := FormatFromMediaType(test.contentType)
+		if test.errContains != "" {
+			if err == nil || !strings.Contains(err.Error(), test.errContains) {
+				t.Errorf("FormatFromMediaType(%q) => %v, %v; want error containing %q", test.contentType, got, err, test.errContains)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("FormatFromMediaType(%q) => %v, %v; want %v", test.contentType, got, err, test.want)
+		}
+	}
+}
+
+func TestDecodeResponse(t *testing.T) {
+	qs, err := DecodeResponse("text/turtle; charset=utf-8", strings.NewReader("@prefix ex: <http://ex/> .\nex:s ex:p ex:o, ex:o2 .\n"))
+	if err != nil || len(qs) != 2 {
+		t.Fatalf("DecodeResponse Turtle => %v, %v; want 2 quads", qs, err)
+	}
+	for _, q := range qs {
+		if !q.InDefaultGraph(defaultGraph) {
+			t.Errorf("DecodeResponse Turtle => %v; want quad in default graph", q)
+		}
+	}
+
+	qs, err = DecodeResponse("application/n-quads", strings.NewReader("<http://ex/s> <http://ex/p> <http://ex/o> <http://ex/g> .\n"))
+	if err != nil || len(qs) != 1 || !TermsEqual(qs[0].Ctx, IRI{str: "http://ex/g"}) {
+		t.Errorf("DecodeResponse N-Quads => %v, %v; want 1 quad in graph <http://ex/g>", qs, err)
+	}
+
+	if _, err := DecodeResponse("text/html", strings.NewReader("<html></html>")); err == nil {
+		t.Errorf("DecodeResponse text/html => <no error>; want error")
+	}
+}
@@ -6,10 +6,19 @@ import "io"
 func (d *QuadDecoder) parseNQ() (q Quad, err error) {
 	defer d.recover(&err)
 
+	if d.max > 0 && d.n >= d.max {
+		// Limit reached; no need to lex the rest of the input.
+		d.l.stop()
+	}
+	if d.l.stopped {
+		return q, io.EOF
+	}
+
 	for d.peek().typ == tokenEOL {
 		d.next()
 	}
 	if d.peek().typ == tokenEOF {
+		d.blanks.report()
 		return q, io.EOF
 	}
 
@@ -22,6 +31,7 @@ func (d *QuadDecoder) parseNQ() (q Quad, err error) {
 		q.Subj = IRI{str: tok.text}
 	} else {
 		q.Subj = Blank{id: tok.text}
+		d.blanks.add(tok)
 	}
 
 	// parse quad predicate
@@ -34,6 +44,7 @@ func (d *QuadDecoder) parseNQ() (q Quad, err error) {
 	switch tok.typ {
 	case tokenBNode:
 		q.Obj = Blank{id: tok.text}
+		d.blanks.add(tok)
 	case tokenLiteral:
 		val := tok.text
 		l := Literal{
@@ -66,6 +77,7 @@ func (d *QuadDecoder) parseNQ() (q Quad, err error) {
 	case tokenBNode:
 		tok = d.next() // consume peeked token
 		q.Ctx = Blank{id: tok.text}
+		d.blanks.add(tok)
 	case tokenDot:
 		break
 	default:
@@ -82,5 +94,11 @@ func (d *QuadDecoder) parseNQ() (q Quad, err error) {
 		// drain lexer
 		d.next()
 	}
+
+	d.n++
+	if d.max > 0 && d.n >= d.max {
+		// Limit reached; no need to lex the rest of the input.
+		d.l.stop()
+	}
 	return q, err
 }
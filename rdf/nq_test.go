@@ -8,8 +8,6 @@ import (
 	"testing"
 )
 
-var defaultGraph = Blank{id: "_:defaultGraph"}
-
 func BenchmarkDecodeNQ(b *testing.B) {
 	input := "#comment\n<http://example/s> <http://example/p> \"123\"^^<http://www.w3.org/2001/XMLSchema#integer> <http://example/g>"
 	for n := 0; n < b.N; n++ {
@@ -816,7 +814,7 @@ _:1a  <http://example/p> <http://example/o> .`, "", []Quad{
 	//   mf:action    <nt-syntax-bad-string-01.nq> ;
 	//   .
 
-	{`<http://example/s> <http://example/p> "abc' .`, "syntax error: bad literal: no closing quote: '\"'", []Quad{}},
+	{`<http://example/s> <http://example/p> "abc' .`, "syntax error: unterminated string literal: no closing quote: '\"'", []Quad{}},
 
 	//<#nt-syntax-bad-string-02> a rdft:TestNQuadsNegativeSyntax ;
 	//   mf:name    "nt-syntax-bad-string-02" ;
@@ -861,7 +859,7 @@ _:1a  <http://example/p> <http://example/o> .`, "", []Quad{
 	//   mf:action    <nt-syntax-bad-string-06.nq> ;
 	//   .
 
-	{`<http://example/s> <http://example/p> "abc .`, "syntax error: bad literal: no closing quote: '\"'", []Quad{}},
+	{`<http://example/s> <http://example/p> "abc .`, "syntax error: unterminated string literal: no closing quote: '\"'", []Quad{}},
 
 	//<#nt-syntax-bad-string-07> a rdft:TestNQuadsNegativeSyntax ;
 	//   mf:name    "nt-syntax-bad-string-07" ;
@@ -1741,3 +1739,104 @@ _:subject2 <http://an.example/predicate2> "object2" <http://example.org/graph5>
 		}
 	}
 }
+
+func TestEncodeNQuadsDefaultGraph(t *testing.T) {
+	input := `<http://example.org/s> <http://example.org/p> "a" <http://example.org/g> .
+<http://example.org/s> <http://example.org/p> "b" .
+_:s <http://example.org/p> _:o _:g .
+_:s <http://example.org/p> _:o .
+`
+	quads, err := NewQuadDecoder(bytes.NewBufferString(input), NQuads).DecodeAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	enc := NewQuadEncoder(&out, NQuads)
+	if err := enc.EncodeAll(quads); err != nil {
+		t.Fatal(err)
+	}
+	enc.Close()
+
+	if out.String() != input {
+		t.Errorf("N-Quads decode-encode roundtrip =>\n%s\nwant:\n%s", out.String(), input)
+	}
+
+	// A custom default graph, shared by decoder and encoder:
+	g := IRI{str: "http://example.org/g"}
+	dec := NewQuadDecoder(bytes.NewBufferString(input), NQuads)
+	dec.DefaultGraph = g
+	quads, err = dec.DecodeAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !quads[0].InDefaultGraph(g) || !quads[1].InDefaultGraph(g) || quads[2].InDefaultGraph(g) {
+		t.Errorf("Quad.InDefaultGraph(%v) => %v, %v, %v; want true, true, false", g,
+			quads[0].InDefaultGraph(g), quads[1].InDefaultGraph(g), quads[2].InDefaultGraph(g))
+	}
+
+	out.Reset()
+	enc = NewQuadEncoder(&out, NQuads)
+	enc.DefaultGraph = g
+	if err := enc.Encode(quads[0]); err != nil {
+		t.Fatal(err)
+	}
+	enc.Close()
+	if want := "<http://example.org/s> <http://example.org/p> \"a\" .\n"; out.String() != want {
+		t.Errorf("encoding quad in custom default graph => %q; want %q", out.String(), want)
+	}
+}
+
+func TestEncodeNQuadsGroupByGraph(t *testing.T) {
+	input := `<http://example.org/s> <http://example.org/p> "1" <http://example.org/g1> .
+<http://example.org/s> <http://example.org/p> "2" .
+<http://example.org/s> <http://example.org/p> "3" _:g2 .
+<http://example.org/s> <http://example.org/p> "4" <http://example.org/g1> .
+<http://example.org/s> <http://example.org/p> "5" .
+`
+	want := `# graph <http://example.org/g1>
+<http://example.org/s> <http://example.org/p> "1" .
+<http://example.org/s> <http://example.org/p> "4" .
+
+# default graph
+<http://example.org/s> <http://example.org/p> "2" .
+<http://example.org/s> <http://example.org/p> "5" .
+
+# graph _:g2
+<http://example.org/s> <http://example.org/p> "3" .
+`
+	quads, err := NewQuadDecoder(bytes.NewBufferString(input), NQuads).DecodeAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	enc := NewQuadEncoder(&out, NQuads)
+	enc.GroupByGraph = true
+	if err := enc.Encode(quads[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.EncodeAll(quads[1:]); err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("QuadEncoder with GroupByGraph wrote output before Close()")
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != want {
+		t.Errorf("encoding N-Quads grouped by graph =>\n%s\nwant:\n%s", out.String(), want)
+	}
+	if err := enc.Encode(quads[0]); err != ErrEncoderClosed {
+		t.Errorf("Encode() after Close() => %v; want ErrEncoderClosed", err)
+	}
+
+	enc = NewQuadEncoder(&out, NQuads)
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Encode(quads[0]); err != ErrEncoderClosed {
+		t.Errorf("Encode() after Close() without GroupByGraph => %v; want ErrEncoderClosed", err)
+	}
+}
@@ -8,9 +8,12 @@ import (
 
 // ntDecoder is a N-Triples parser.
 type ntDecoder struct {
-	l         *lexer   // Turtle lexer (N-Triples is a subset of Turtle)
-	tokens    [2]token // 2 token lookahead
-	peekCount int      // Number of tokens peeked at (position in tokens lookahead array)
+	l         *lexer        // Turtle lexer (N-Triples is a subset of Turtle)
+	tokens    [2]token      // 2 token lookahead
+	peekCount int           // Number of tokens peeked at (position in tokens lookahead array)
+	max       int           // Maximum number of triples to decode (0 for no limit)
+	n         int           // Number of triples decoded
+	blanks    *blankCounter // Counts blank node labels, when warning about singletons (nil otherwise)
 }
 
 // newNTDecoder returns a new N-Triples parser on the given io.Reader.
@@ -22,12 +25,21 @@ func newNTDecoder(r io.Reader) *ntDecoder {
 func (d *ntDecoder) Decode() (t Triple, err error) {
 	defer d.recover(&err)
 
+	if d.max > 0 && d.n >= d.max {
+		// Limit reached; no need to lex the rest of the input.
+		d.l.stop()
+	}
+	if d.l.stopped {
+		return t, io.EOF
+	}
+
 again:
 	for d.peek().typ == tokenEOL {
 		d.next()
 		goto again
 	}
 	if d.peek().typ == tokenEOF {
+		d.blanks.report()
 		return t, io.EOF
 	}
 
@@ -37,6 +49,7 @@ again:
 		t.Subj = IRI{str: tok.text}
 	} else {
 		t.Subj = Blank{id: tok.text}
+		d.blanks.add(tok)
 	}
 
 	// parse triple predicate
@@ -49,6 +62,7 @@ again:
 	switch tok.typ {
 	case tokenBNode:
 		t.Obj = Blank{id: tok.text}
+		d.blanks.add(tok)
 	case tokenLiteral:
 		val := tok.text
 		l := Literal{
@@ -83,6 +97,12 @@ again:
 		d.next()
 	}
 
+	d.n++
+	if d.max > 0 && d.n >= d.max {
+		// Limit reached; no need to lex the rest of the input.
+		d.l.stop()
+	}
+
 	return t, err
 }
 
@@ -102,9 +122,22 @@ func (d *ntDecoder) DecodeAll() ([]Triple, error) {
 // SetOption sets a ParseOption to the give value
 func (d *ntDecoder) SetOption(o ParseOption, v interface{}) error {
 	switch o {
+	case MaxTriples:
+		n, err := maxTriplesOption(v)
+		if err != nil {
+			return err
+		}
+		d.max = n
+	case ErrOut:
+		w, err := errOutOption(v)
+		if err != nil {
+			return err
+		}
+		d.blanks = newBlankCounter(w, NTriples)
 	default:
 		return fmt.Errorf("N-Triples decoder doesn't support option: %v", o)
 	}
+	return nil
 }
 
 // Parsing functions:
@@ -140,7 +173,7 @@ func (d *ntDecoder) recover(errp *error) {
 			panic(e)
 		}
 		//d.stop() something to clean up?
-		*errp = e.(error)
+		*errp = formatErr(e.(error), NTriples)
 	}
 	return
 }
@@ -153,18 +186,18 @@ func (d *ntDecoder) errorf(format string, args ...interface{}) {
 
 // unexpected complains about the given token and terminates parsing.
 func (d *ntDecoder) unexpected(t token, context string) {
-	d.errorf("%d:%d unexpected %v as %s", t.line, t.col, t.typ, context)
+	if t.typ == tokenEOL && d.peek().typ == tokenEOF {
+		// The line was cut short by the end of input.
+		t.typ = tokenEOF
+	}
+	panic(unexpectedErr(t, context))
 }
 
 // expect1As consumes the next token and guarantees that it has the expected type.
 func (d *ntDecoder) expect1As(context string, expected tokenType) token {
 	t := d.next()
 	if t.typ != expected {
-		if t.typ == tokenError {
-			d.errorf("%d:%d: syntax error: %s", t.line, t.col, t.text)
-		} else {
-			d.unexpected(t, context)
-		}
+		d.unexpected(t, context)
 	}
 	return t
 }
@@ -177,10 +210,6 @@ func (d *ntDecoder) expectAs(context string, expected ...tokenType) token {
 			return t
 		}
 	}
-	if t.typ == tokenError {
-		d.errorf("syntax error: %s", t.text)
-	} else {
-		d.unexpected(t, context)
-	}
+	d.unexpected(t, context)
 	return t
 }
@@ -736,7 +736,7 @@ var ntTestSuite = []struct {
 	//   mf:action    <nt-syntax-bad-string-01.nt> ;
 	//   .
 
-	{`<http://example/s> <http://example/p> "abc' .`, "syntax error: bad literal: no closing quote: '\"'", nil},
+	{`<http://example/s> <http://example/p> "abc' .`, "syntax error: unterminated string literal: no closing quote: '\"'", nil},
 
 	//<#nt-syntax-bad-string-02> rdf:type rdft:TestNTriplesNegativeSyntax ;
 	//   mf:name    "nt-syntax-bad-string-02" ;
@@ -776,7 +776,7 @@ var ntTestSuite = []struct {
 	//   mf:action    <nt-syntax-bad-string-06.nt> ;
 	//   .
 
-	{`<http://example/s> <http://example/p> "abc .`, "syntax error: bad literal: no closing quote: '\"'", nil},
+	{`<http://example/s> <http://example/p> "abc .`, "syntax error: unterminated string literal: no closing quote: '\"'", nil},
 
 	//<#nt-syntax-bad-string-07> rdf:type rdft:TestNTriplesNegativeSyntax ;
 	//   mf:name    "nt-syntax-bad-string-07" ;
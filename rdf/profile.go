@@ -0,0 +1,131 @@
+package rdf
+
+import (
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Range describes the literal objects of a predicate, as found by
+// PredicateRanges. Numeric and temporal values are ranged separately, since
+// they cannot be compared with each other.
+type Range struct {
+	DataTypes []IRI // distinct datatypes of the literal objects, sorted
+
+	Numeric  bool    // true if a numeric literal was seen, so Min and Max are set
+	Min, Max float64 // smallest and largest numeric value
+
+	Temporal         bool      // true if a date or dateTime literal was seen, so Earliest and Latest are set
+	Earliest, Latest time.Time // earliest and latest date or dateTime value
+}
+
+// PredicateRanges decodes all the triples of d, and returns a Range for each
+// predicate, keyed by the predicate IRI. Literals of xsd:decimal, xsd:float,
+// xsd:double and the integer datatypes derived from xsd:decimal (xsd:integer,
+// xsd:int, xsd:long, xsd:nonNegativeInteger and so on) are numeric, and
+// literals of xsd:date and xsd:dateTime are temporal. Literals which are not
+// valid for their datatype, and NaN, only count towards the datatypes.
+//
+// The document is streamed once, so memory use is proportional to the number
+// of distinct predicates and datatypes, not to the size of the document. It
+// is meant for profiling a dataset, e.g. for deriving the xsd facets of its
+// schema (xsd:minInclusive, xsd:maxInclusive).
+func PredicateRanges(d TripleDecoder) (map[string]Range, error) {
+	ranges := make(map[string]Range)
+	types := make(map[string]map[IRI]struct{})
+	for t, err := d.Decode(); err != io.EOF; t, err = d.Decode() {
+		if err != nil {
+			return nil, err
+		}
+		p := t.Pred.(IRI).str
+		r := ranges[p]
+		if types[p] == nil {
+			types[p] = make(map[IRI]struct{})
+		}
+		l, ok := t.Obj.(Literal)
+		if !ok {
+			ranges[p] = r
+			continue
+		}
+		types[p][l.DataType] = struct{}{}
+		if v, ok := numericValue(l); ok {
+			r.addNumber(v)
+		} else if l.DataType == xsdDate || l.DataType == xsdDateTime {
+			if v, err := parseLiteral(l.str, l.DataType.str); err == nil {
+				r.addTime(v.(time.Time))
+			}
+		}
+		ranges[p] = r
+	}
+
+	for p, r := range ranges {
+		for dt := range types[p] {
+			r.DataTypes = append(r.DataTypes, dt)
+		}
+		sort.Slice(r.DataTypes, func(i, j int) bool { return r.DataTypes[i].str < r.DataTypes[j].str })
+		ranges[p] = r
+	}
+	return ranges, nil
+}
+
+// xsdIntegerTypes are the local names of the xsd integer datatypes, that is,
+// xsd:integer and the datatypes derived from it.
+var xsdIntegerTypes = map[string]bool{
+	"integer": true, "nonPositiveInteger": true, "negativeInteger": true,
+	"long": true, "int": true, "short": true, "byte": true,
+	"nonNegativeInteger": true, "positiveInteger": true,
+	"unsignedLong": true, "unsignedInt": true, "unsignedShort": true, "unsignedByte": true,
+}
+
+// numericValue returns the value of a literal of a numeric xsd datatype, and
+// false if the literal is not numeric, or not valid for its datatype.
+func numericValue(l Literal) (float64, bool) {
+	const xsd = "http://www.w3.org/2001/XMLSchema#"
+	dt := l.DataType.str
+	if !strings.HasPrefix(dt, xsd) {
+		return 0, false
+	}
+	switch local := dt[len(xsd):]; {
+	case xsdIntegerTypes[local]:
+		digits := strings.TrimLeft(l.str, "+-")
+		if len(l.str)-len(digits) > 1 || digits == "" || strings.Trim(digits, "0123456789") != "" {
+			return 0, false
+		}
+	case local == "decimal":
+		if strings.ContainsAny(l.str, "eEnN") {
+			return 0, false // no exponent, INF or NaN
+		}
+	case local != "float" && local != "double":
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(l.str, 64)
+	if err != nil || math.IsNaN(v) {
+		return 0, false
+	}
+	return v, true
+}
+
+// addNumber extends the numeric range to include v.
+func (r *Range) addNumber(v float64) {
+	if !r.Numeric || v < r.Min {
+		r.Min = v
+	}
+	if !r.Numeric || v > r.Max {
+		r.Max = v
+	}
+	r.Numeric = true
+}
+
+// addTime extends the temporal range to include v.
+func (r *Range) addTime(v time.Time) {
+	if !r.Temporal || v.Before(r.Earliest) {
+		r.Earliest = v
+	}
+	if !r.Temporal || v.After(r.Latest) {
+		r.Latest = v
+	}
+	r.Temporal = true
+}
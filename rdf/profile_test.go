@@ -0,0 +1,59 @@
+package rdf
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPredicateRanges(t *testing.T) {
+	input := `@prefix ex: <http://example.org/> .
+@prefix xsd: <http://www.w3.org/2001/XMLSchema#> .
+ex:a ex:age 42 ; ex:born "1980-05-01"^^xsd:date ; ex:name "A" ; ex:knows ex:b .
+ex:b ex:age 7 ; ex:born "2001-12-24T10:00:00Z"^^xsd:dateTime ; ex:name "B"@en .
+ex:c ex:age 19.5 ; ex:age "unknown" ; ex:age "x"^^xsd:integer .
+ex:d ex:size "5"^^xsd:int ; ex:size "9"^^xsd:unsignedShort ; ex:size "-2.5E1"^^xsd:float ; ex:size "1.5"^^xsd:int .`
+
+	ranges, err := PredicateRanges(NewTripleDecoder(strings.NewReader(input), Turtle))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	xsd := func(local string) IRI { return IRI{str: "http://www.w3.org/2001/XMLSchema#" + local} }
+	want := map[string]Range{
+		"http://example.org/age": {
+			DataTypes: []IRI{xsd("decimal"), xsd("integer"), xsd("string")},
+			Numeric:   true, Min: 7, Max: 42,
+		},
+		"http://example.org/born": {
+			DataTypes: []IRI{xsd("date"), xsd("dateTime")},
+			Temporal:  true,
+			Earliest:  time.Date(1980, 5, 1, 0, 0, 0, 0, time.UTC),
+			Latest:    time.Date(2001, 12, 24, 10, 0, 0, 0, time.UTC),
+		},
+		"http://example.org/name": {
+			DataTypes: []IRI{rdfLangString, xsd("string")},
+		},
+		"http://example.org/knows": {},
+		"http://example.org/size": {
+			DataTypes: []IRI{xsd("float"), xsd("int"), xsd("unsignedShort")},
+			Numeric:   true, Min: -25, Max: 9,
+		},
+	}
+	if len(ranges) != len(want) {
+		t.Errorf("PredicateRanges => %d predicates; want %d", len(ranges), len(want))
+	}
+	for p, w := range want {
+		got, ok := ranges[p]
+		if !ok {
+			t.Errorf("PredicateRanges => no range for %s", p)
+			continue
+		}
+		if !reflect.DeepEqual(got.DataTypes, w.DataTypes) ||
+			got.Numeric != w.Numeric || got.Min != w.Min || got.Max != w.Max ||
+			got.Temporal != w.Temporal || !got.Earliest.Equal(w.Earliest) || !got.Latest.Equal(w.Latest) {
+			t.Errorf("PredicateRanges[%s] =>\n%+v\nwant:\n%+v", p, got, w)
+		}
+	}
+}
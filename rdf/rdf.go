@@ -39,8 +39,10 @@
 package rdf
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -68,7 +70,7 @@ var (
 
 	// Time and date:
 
-	//xsdDate = IRI{str: "http://www.w3.org/2001/XMLSchema#date"}
+	xsdDate = IRI{str: "http://www.w3.org/2001/XMLSchema#date"} // time.Time
 	//xsdTime          = IRI{str: "http://www.w3.org/2001/XMLSchema#time"}
 	xsdDateTime = IRI{str: "http://www.w3.org/2001/XMLSchema#dateTime"} // time.Time
 	//xsdDateTimeStamp = IRI{str: "http://www.w3.org/2001/XMLSchema#dateTimeStamp"}
@@ -94,8 +96,18 @@ var (
 
 	rdfLangString = IRI{str: "http://www.w3.org/1999/02/22-rdf-syntax-ns#langString"} // string
 	xmlLiteral    = IRI{str: "http://www.w3.org/1999/02/22-rdf-syntax-ns#XMLLiteral"} // string
+	xsdAnyURI     = IRI{str: "http://www.w3.org/2001/XMLSchema#anyURI"}               // string, or *url.URL by Literal.URL
 )
 
+// RDFType is the rdf:type predicate. All decoders represent rdf:type with this
+// IRI, including Turtle's 'a' keyword.
+var RDFType = IRI{str: "http://www.w3.org/1999/02/22-rdf-syntax-ns#type"}
+
+// RDFNil is rdf:nil, the empty list. The Turtle decoder represents the empty
+// collection () with this IRI, and the Turtle encoder writes it back as ()
+// when its Collections field is set.
+var RDFNil = IRI{str: "http://www.w3.org/1999/02/22-rdf-syntax-ns#nil"}
+
 // Format represents a RDF serialization format.
 type Format int
 
@@ -106,18 +118,39 @@ const (
 	NTriples Format = iota
 	Turtle
 	RDFXML
-	// TODO: JSON-LD
+	// TODO: JSON-LD. The encoder should default to compact, single-line
+	// output, with a SetIndent(prefix, indent string) method for pretty-printing,
+	// following the conventions of encoding/json.
 
 	// Quad serialization:
 
 	NQuads // N-Quads
-	// TODO: Format TriG
+	// TODO: Format TriG. The decoder must accept empty graph blocks
+	// (<g> {}), which are valid TriG, and register the graph with
+	// Dataset.AddGraph when decoding into a Dataset.
 
 	// Internal formats
 	formatInternal
 )
 
-// Term represents an RDF term. There are 3 term types: Blank node, Literal and IRI.
+// String returns the name of the serialization format.
+func (f Format) String() string {
+	switch f {
+	case NTriples:
+		return "N-Triples"
+	case Turtle:
+		return "Turtle"
+	case RDFXML:
+		return "RDF/XML"
+	case NQuads:
+		return "N-Quads"
+	default:
+		return fmt.Sprintf("Format(%d)", int(f))
+	}
+}
+
+// Term represents an RDF term. There are 3 term types: Blank node, Literal and IRI,
+// plus the RDF-star quoted triple.
 type Term interface {
 	// Serialize returns a string representation of the Term in the specified serialization format.
 	Serialize(Format) string
@@ -127,6 +160,43 @@ type Term interface {
 
 	// Type returns the Term type.
 	Type() TermType
+
+	// Bytes returns a canonical binary encoding of the Term, for use in
+	// hashing, or as a map key, without going through Serialize. The
+	// encoding is exact: two terms have the same encoding if and only if
+	// they are identical, so terms which are equal by value, as by
+	// TermsEqual, like "1"^^xsd:integer and "1"^^xsd:decimal, may be
+	// encoded differently. See termBytes for the layout, which is kept
+	// stable across versions of this package, so the encodings can be
+	// stored.
+	//
+	// Bytes was added to Term after its other methods, which breaks any
+	// implementation of Term outside this package. Such implementations
+	// could not be used in triples anyway, as the Subject, Predicate and
+	// Object interfaces have unexported methods.
+	Bytes() []byte
+}
+
+// termBytes returns the encoding of a term, as returned by Term.Bytes:
+// a single byte with the TermType, followed by the fields of the term,
+// each prefixed by its length in bytes as an unsigned varint:
+//
+//	Blank:         label (without "_:")
+//	IRI:           IRI
+//	Literal:       lexical form, datatype IRI, language tag (empty if none)
+//	QuotedTriple:  subject, predicate and object, each encoded as a Term
+func termBytes(typ TermType, fields ...[]byte) []byte {
+	n := 1
+	for _, f := range fields {
+		n += binary.MaxVarintLen64 + len(f)
+	}
+	b := make([]byte, 1, n)
+	b[0] = byte(typ)
+	for _, f := range fields {
+		b = binary.AppendUvarint(b, uint64(len(f)))
+		b = append(b, f...)
+	}
+	return b
 }
 
 // TermType describes the type of RDF term: Blank node, IRI or Literal
@@ -137,6 +207,7 @@ const (
 	TermBlank TermType = iota
 	TermIRI
 	TermLiteral
+	TermQuotedTriple
 )
 
 // Blank represents a RDF blank node; an unqualified IRI with identified by a label.
@@ -165,6 +236,11 @@ func (b Blank) String() string {
 	return b.id[2:]
 }
 
+// Bytes returns the canonical binary encoding of a Blank node.
+func (b Blank) Bytes() []byte {
+	return termBytes(TermBlank, []byte(strings.TrimPrefix(b.id, "_:")))
+}
+
 // NewBlank returns a new blank node with a given label. It returns
 // an error only if the supplied label is blank.
 func NewBlank(id string) (Blank, error) {
@@ -193,6 +269,11 @@ func (u IRI) Type() TermType {
 	return TermIRI
 }
 
+// Bytes returns the canonical binary encoding of an IRI.
+func (u IRI) Bytes() []byte {
+	return termBytes(TermIRI, []byte(u.str))
+}
+
 // String returns the IRI string.
 func (u IRI) String() string {
 	return u.str
@@ -288,11 +369,46 @@ func (l Literal) Type() TermType {
 	return TermLiteral
 }
 
+// Bytes returns the canonical binary encoding of a Literal.
+func (l Literal) Bytes() []byte {
+	return termBytes(TermLiteral, []byte(l.str), []byte(l.DataType.str), []byte(l.lang))
+}
+
 // Lang returns the language of a language-tagged string.
 func (l Literal) Lang() string {
 	return l.lang
 }
 
+// CanonicalLang returns the Literal with its language tag in the casing
+// recommended by BCP 47 (RFC 5646, section 2.1.1): the language and any
+// other subtags lowercase, the script subtag titlecase, and the region
+// subtag uppercase. For example: "ZH-hant-hk" => "zh-Hant-HK".
+//
+// Subtags after a singleton (an extension or private use subtag) are
+// lowercased. Literals without a language tag are returned unchanged.
+func (l Literal) CanonicalLang() Literal {
+	if l.lang == "" {
+		return l
+	}
+	subtags := strings.Split(strings.ToLower(l.lang), "-")
+	for i, st := range subtags {
+		if len(st) == 1 {
+			// Singleton; the rest of the subtags keep lowercase.
+			break
+		}
+		switch {
+		case i == 0:
+			// Language subtag
+		case len(st) == 2:
+			subtags[i] = strings.ToUpper(st)
+		case len(st) == 4 && isAlpha(rune(st[0])):
+			subtags[i] = strings.ToUpper(st[:1]) + st[1:]
+		}
+	}
+	l.lang = strings.Join(subtags, "-")
+	return l
+}
+
 // String returns the literal string.
 func (l Literal) String() string {
 	return l.str
@@ -326,6 +442,8 @@ func (l Literal) Typed() (interface{}, error) {
 			return b, nil
 		case xsdByte.str:
 			return []byte(l.str), nil
+		case xsdAnyURI.str:
+			return l.str, nil
 			// TODO xsdDateTime etc
 		default:
 			return l.str, nil
@@ -334,6 +452,16 @@ func (l Literal) Typed() (interface{}, error) {
 	return l.val, nil
 }
 
+// URL parses the value of an xsd:anyURI literal as a URL. It returns an error
+// if the literal has another datatype. The literal itself stays a Literal,
+// and never becomes an IRI term.
+func (l Literal) URL() (*url.URL, error) {
+	if l.DataType != xsdAnyURI {
+		return nil, fmt.Errorf("literal of datatype %s is not an xsd:anyURI", l.DataType.str)
+	}
+	return url.Parse(l.str)
+}
+
 // validAsObject denotes that a Literal is valid as a Triple's Object.
 func (l Literal) validAsObject() {}
 
@@ -353,6 +481,11 @@ func NewLiteral(v interface{}) (Literal, error) {
 		return Literal{val: t, str: t.Format(DateFormat), DataType: xsdDateTime}, nil
 	case []byte:
 		return Literal{val: t, str: string(t), DataType: xsdByte}, nil
+	case *url.URL:
+		if t == nil {
+			return Literal{}, errors.New("cannot make a literal from a nil *url.URL")
+		}
+		return Literal{str: t.String(), DataType: xsdAnyURI}, nil
 	default:
 		return Literal{}, fmt.Errorf("cannot infer XSD datatype from %#v", t)
 	}
@@ -396,6 +529,42 @@ func NewTypedLiteral(v string, dt IRI) Literal {
 	return Literal{str: v, DataType: dt}
 }
 
+// QuotedTriple represents a RDF-star quoted triple; a Triple used as the
+// subject or object of another Triple. Quoting a triple does not assert it.
+type QuotedTriple struct {
+	Triple
+}
+
+// validAsSubject denotes that a QuotedTriple is valid as a Triple's Subject.
+func (q QuotedTriple) validAsSubject() {}
+
+// validAsObject denotes that a QuotedTriple is valid as a Triple's Object.
+func (q QuotedTriple) validAsObject() {}
+
+// Type returns the TermType of a QuotedTriple.
+func (q QuotedTriple) Type() TermType {
+	return TermQuotedTriple
+}
+
+// Bytes returns the canonical binary encoding of a QuotedTriple.
+func (q QuotedTriple) Bytes() []byte {
+	return termBytes(TermQuotedTriple, q.Subj.Bytes(), q.Pred.Bytes(), q.Obj.Bytes())
+}
+
+// Serialize returns a string representation of a QuotedTriple: << s p o >>
+func (q QuotedTriple) Serialize(f Format) string {
+	if f == formatInternal {
+		// The terms must be told apart exactly, see TermsEqual.
+		f = NTriples
+	}
+	return fmt.Sprintf("<< %s %s %s >>", q.Subj.Serialize(f), q.Pred.Serialize(f), q.Obj.Serialize(f))
+}
+
+// String returns the quoted triple in N-Triples-star syntax.
+func (q QuotedTriple) String() string {
+	return q.Serialize(NTriples)
+}
+
 // Subject interface distiguishes which Terms are valid as a Subject of a Triple.
 type Subject interface {
 	Term
@@ -439,6 +608,8 @@ func (t Triple) Serialize(f Format) string {
 		s = term.Serialize(f)
 	case Blank:
 		s = term.Serialize(f)
+	case QuotedTriple:
+		s = term.Serialize(f)
 	}
 	switch term := t.Obj.(type) {
 	case IRI:
@@ -447,6 +618,8 @@ func (t Triple) Serialize(f Format) string {
 		o = term.Serialize(f)
 	case Blank:
 		o = term.Serialize(f)
+	case QuotedTriple:
+		o = term.Serialize(f)
 	}
 	return fmt.Sprintf(
 		"%s %s %s .\n",
@@ -456,6 +629,21 @@ func (t Triple) Serialize(f Format) string {
 	)
 }
 
+// Terms returns the subject, predicate and object of the Triple, in that order.
+func (t Triple) Terms() [3]Term {
+	return [3]Term{t.Subj, t.Pred, t.Obj}
+}
+
+// IsType returns true if the predicate of the Triple is rdf:type.
+func (t Triple) IsType() bool {
+	return TermsEqual(t.Pred, RDFType)
+}
+
+// defaultGraph is the context denoting the default graph, used by default by
+// QuadDecoder, QuadEncoder and Dataset, so that quads pass between them in the
+// right graph.
+var defaultGraph = Blank{id: "_:defaultGraph"}
+
 // Quad represents a RDF Quad; a Triple plus the context in which it occurs.
 type Quad struct {
 	Triple
@@ -470,6 +658,8 @@ func (q Quad) Serialize(f Format) string {
 		s = term.Serialize(f)
 	case Blank:
 		s = term.Serialize(f)
+	case QuotedTriple:
+		s = term.Serialize(f)
 	}
 	switch term := q.Obj.(type) {
 	case IRI:
@@ -478,6 +668,8 @@ func (q Quad) Serialize(f Format) string {
 		o = term.Serialize(f)
 	case Blank:
 		o = term.Serialize(f)
+	case QuotedTriple:
+		o = term.Serialize(f)
 	}
 	switch term := q.Ctx.(type) {
 	case IRI:
@@ -494,6 +686,20 @@ func (q Quad) Serialize(f Format) string {
 	)
 }
 
+// Terms returns the subject, predicate, object and context of the Quad, in that order.
+func (q Quad) Terms() [4]Term {
+	return [4]Term{q.Subj, q.Pred, q.Obj, q.Ctx}
+}
+
+// InDefaultGraph returns true if the Quad belongs to the given default graph,
+// or has no context at all.
+func (q Quad) InDefaultGraph(dg Context) bool {
+	if q.Ctx == nil {
+		return true
+	}
+	return dg != nil && TermsEqual(q.Ctx, dg)
+}
+
 // TermsEqual returns true if two Terms are equal, or false if they are not.
 func TermsEqual(a, b Term) bool {
 	if a.Type() != b.Type() {
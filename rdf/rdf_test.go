@@ -1,7 +1,10 @@
 package rdf
 
 import (
+	"bytes"
 	"fmt"
+	"net/url"
+	"strings"
 	"testing"
 )
 
@@ -98,3 +101,183 @@ func TestLiteral(t *testing.T) {
 
 	}
 }
+
+func TestCanonicalLang(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"EN", "en"},
+		{"en-us", "en-US"},
+		{"ZH-hant-hk", "zh-Hant-HK"},
+		{"sr-LATN", "sr-Latn"},
+		{"es-419", "es-419"},
+		{"de-ch-1901", "de-CH-1901"},
+		{"en-A-BB-CCCC", "en-a-bb-cccc"},
+		{"X-Private-US", "x-private-us"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		l := Literal{str: "s", lang: tt.in, DataType: rdfLangString}
+		if tt.in == "" {
+			l.DataType = xsdString
+		}
+		got := l.CanonicalLang()
+		if got.Lang() != tt.want {
+			t.Errorf("Literal with lang %q: CanonicalLang().Lang() => %q; want %q", tt.in, got.Lang(), tt.want)
+		}
+		if got.String() != l.String() || got.DataType != l.DataType {
+			t.Errorf("CanonicalLang() changed more than the language tag: %v => %v", l, got)
+		}
+	}
+}
+
+func TestTerms(t *testing.T) {
+	s := IRI{str: "http://example.org/s"}
+	p := IRI{str: "http://example.org/p"}
+	o := Literal{str: "o", DataType: xsdString}
+	g := Blank{id: "_:g"}
+
+	q := Quad{Triple: Triple{Subj: s, Pred: p, Obj: o}, Ctx: g}
+	want := []Term{s, p, o, g}
+
+	for i, term := range q.Triple.Terms() {
+		if !TermsEqual(term, want[i]) {
+			t.Errorf("Triple.Terms()[%d] => %v; want %v", i, term, want[i])
+		}
+	}
+	for i, term := range q.Terms() {
+		if !TermsEqual(term, want[i]) {
+			t.Errorf("Quad.Terms()[%d] => %v; want %v", i, term, want[i])
+		}
+	}
+}
+
+func TestRDFType(t *testing.T) {
+	tests := []struct {
+		format Format
+		input  string
+	}{
+		{NTriples, `<http://ex/s> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://ex/C> .`},
+		{Turtle, `<http://ex/s> a <http://ex/C> .`},
+		{Turtle, `@prefix rdf: <http://www.w3.org/1999/02/22-rdf-syntax-ns#> . <http://ex/s> rdf:type <http://ex/C> .`},
+		{RDFXML, `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns:ex="http://ex/"><ex:C rdf:about="http://ex/s"/></rdf:RDF>`},
+		{RDFXML, `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"><rdf:Description rdf:about="http://ex/s"><rdf:type rdf:resource="http://ex/C"/></rdf:Description></rdf:RDF>`},
+	}
+	for _, test := range tests {
+		ts, err := NewTripleDecoder(strings.NewReader(test.input), test.format).DecodeAll()
+		if err != nil || len(ts) != 1 {
+			t.Fatalf("decoding %s => %v, %v; want 1 triple", test.input, ts, err)
+		}
+		if ts[0].Pred != RDFType || !ts[0].IsType() {
+			t.Errorf("decoding %s => predicate %v, IsType() => %v; want %v, true", test.input, ts[0].Pred, ts[0].IsType(), RDFType)
+		}
+	}
+
+	if tr := (Triple{Subj: IRI{str: "http://ex/s"}, Pred: IRI{str: "http://ex/type"}, Obj: IRI{str: "http://ex/C"}}); tr.IsType() {
+		t.Errorf("%v.IsType() => true; want false", tr)
+	}
+}
+
+func TestAnyURILiteral(t *testing.T) {
+	const uri = "http://example.org/a%20b?q=1#frag"
+	tests := []struct {
+		format Format
+		input  string
+	}{
+		{NTriples, `<http://ex/s> <http://ex/p> "` + uri + `"^^<http://www.w3.org/2001/XMLSchema#anyURI> .`},
+		{Turtle, `@prefix xsd: <http://www.w3.org/2001/XMLSchema#> . <http://ex/s> <http://ex/p> "` + uri + `"^^xsd:anyURI .`},
+		{RDFXML, `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"><rdf:Description rdf:about="http://ex/s"><p xmlns="http://ex/" rdf:datatype="http://www.w3.org/2001/XMLSchema#anyURI">` + uri + `</p></rdf:Description></rdf:RDF>`},
+	}
+	for _, test := range tests {
+		ts, err := NewTripleDecoder(strings.NewReader(test.input), test.format).DecodeAll()
+		if err != nil || len(ts) != 1 {
+			t.Fatalf("decoding %s => %v, %v; want 1 triple", test.input, ts, err)
+		}
+		l, ok := ts[0].Obj.(Literal)
+		if !ok || ts[0].Obj.Type() != TermLiteral {
+			t.Fatalf("decoding %s => object %#v; want Literal", test.input, ts[0].Obj)
+		}
+		if l.DataType != xsdAnyURI || l.String() != uri {
+			t.Errorf("decoding %s => %q^^%v; want %q^^%v", test.input, l.String(), l.DataType, uri, xsdAnyURI)
+		}
+		if v, err := l.Typed(); err != nil || v != uri {
+			t.Errorf("%v.Typed() => %#v, %v; want %q", l, v, err, uri)
+		}
+
+		// Round-trip through the encodable triple formats.
+		for _, f := range []Format{NTriples, Turtle} {
+			var buf bytes.Buffer
+			enc := NewTripleEncoder(&buf, f)
+			if err := enc.EncodeAll(ts); err != nil {
+				t.Fatal(err)
+			}
+			enc.Close()
+			got, err := NewTripleDecoder(&buf, f).DecodeAll()
+			if err != nil || len(got) != 1 || !TermsEqual(got[0].Obj, l) || got[0].Obj.Type() != TermLiteral {
+				t.Errorf("round-trip of %v through %v => %v, %v", ts[0], f, got, err)
+			}
+		}
+	}
+
+	u, err := NewTypedLiteral(uri, xsdAnyURI).URL()
+	if err != nil || u.String() != uri {
+		t.Errorf("URL() => %v, %v; want %q", u, err, uri)
+	}
+	if _, err := NewTypedLiteral(uri, xsdString).URL(); err == nil {
+		t.Errorf("URL() of xsd:string literal => <no error>; want error")
+	}
+
+	l, err := NewLiteral(u)
+	if err != nil || l.DataType != xsdAnyURI || l.String() != uri {
+		t.Errorf("NewLiteral(%v) => %v, %v; want %q^^%v", u, l, err, uri, xsdAnyURI)
+	}
+	if _, err := NewLiteral((*url.URL)(nil)); err == nil {
+		t.Errorf("NewLiteral(nil *url.URL) => <no error>; want error")
+	}
+}
+
+func TestTermBytes(t *testing.T) {
+	lit := func(str, dt, lang string) Literal { return Literal{str: str, DataType: IRI{str: dt}, lang: lang} }
+	q := func(s Subject, p Predicate, o Object) QuotedTriple {
+		return QuotedTriple{Triple{Subj: s, Pred: p, Obj: o}}
+	}
+
+	// All distinct terms, including ones which would be ambiguous
+	// without the type tag and length prefixes.
+	terms := []Term{
+		IRI{str: "x"},
+		Blank{id: "_:x"},
+		lit("x", xsdString.str, ""),
+		lit("x", rdfLangString.str, "en"),
+		lit("x", rdfLangString.str, "en-gb"),
+		lit("ab", "c", ""),
+		lit("a", "bc", ""),
+		lit("", "", ""),
+		IRI{str: ""},
+		Blank{},
+		q(IRI{str: "s"}, IRI{str: "p"}, IRI{str: "o"}),
+		q(IRI{str: "s"}, IRI{str: "p"}, Blank{id: "_:o"}),
+		q(IRI{str: "s"}, IRI{str: "p"}, q(IRI{str: "s"}, IRI{str: "p"}, IRI{str: "o"})),
+	}
+	seen := make(map[string]Term)
+	for _, term := range terms {
+		k := string(term.Bytes())
+		if other, ok := seen[k]; ok {
+			t.Errorf("%#v and %#v have the same encoding: %q", term, other, k)
+		}
+		seen[k] = term
+	}
+
+	// Equal terms have equal encodings.
+	a, _ := NewLangLiteral("hei", "no")
+	b, _ := NewLangLiteral("hei", "no")
+	if !bytes.Equal(a.Bytes(), b.Bytes()) {
+		t.Errorf("%v.Bytes() => %q, %q; want equal", a, a.Bytes(), b.Bytes())
+	}
+
+	// The encoding is stable.
+	want := []byte("\x03" + "\x03\x00\x01s" + "\x03\x01\x01p" + "\x2d\x02\x011\x28" + xsdInteger.str + "\x00")
+	if got := q(Blank{id: "_:s"}, IRI{str: "p"}, lit("1", xsdInteger.str, "")).Bytes(); !bytes.Equal(got, want) {
+		t.Errorf("Bytes() => %q; want %q", got, want)
+	}
+}
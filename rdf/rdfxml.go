@@ -40,10 +40,8 @@ const (
 )
 
 var (
-	rdfType      = IRI{str: "http://www.w3.org/1999/02/22-rdf-syntax-ns#type"}
 	rdfFirst     = IRI{str: "http://www.w3.org/1999/02/22-rdf-syntax-ns#first"}
 	rdfRest      = IRI{str: "http://www.w3.org/1999/02/22-rdf-syntax-ns#rest"}
-	rdfNil       = IRI{str: "http://www.w3.org/1999/02/22-rdf-syntax-ns#nil"}
 	rdfSubj      = IRI{str: "http://www.w3.org/1999/02/22-rdf-syntax-ns#subject"}
 	rdfPred      = IRI{str: "http://www.w3.org/1999/02/22-rdf-syntax-ns#predicate"}
 	rdfObj       = IRI{str: "http://www.w3.org/1999/02/22-rdf-syntax-ns#object"}
@@ -59,6 +57,15 @@ type evalCtx struct {
 	Lang string
 	LiN  int
 	NS   []string
+	Coll *xmlColl // non-nil when parsing the node elements of a collection
+}
+
+// xmlColl keeps track of the rdf:List beeing built from a property element
+// with parseType="Collection".
+type xmlColl struct {
+	subj Subject   // subject of the property element
+	pred Predicate // the property element
+	last Blank     // the last list node, or the zero value if no items yet
 }
 
 // rdfXMLDecoder decodes Triples from an XML stream.
@@ -75,21 +82,35 @@ type rdfXMLDecoder struct {
 	nextState parseXMLFn // which state function enter on the next call to Decode()
 	ns        []string   // prefix and namespaces (only from the top-level element, usually rdf:RDF)
 	base      string     // top level xml:base
+	lang      string     // top level xml:lang
 	bnodeN    int        // anonymous blank node counter
 	tok       xml.Token  // current XML token
 	topElem   string     // top level element (namespace+localname)
 	reifyID   string     // if not "", id to be resolved against the current in-scope Base IRI
 	dt        *IRI       // datatype of the Literal to be parsed
-	lang      string     // xml element in-scope xml:lang
 	current   Triple     // the current triple beeing parsed
 	ctx       evalCtx    // current node evaluation context
 	ctxStack  []evalCtx  // stack of parent evaluation contexts
+	max       int        // maximum number of triples to decode (0 for no limit)
+	n         int        // number of triples decoded
+	limited   bool       // true when max has been reached, and decoding stopped
 
 	triples []Triple // complete, valid triples to be emitted
 }
 
 func newRDFXMLDecoder(r io.Reader) *rdfXMLDecoder {
-	return &rdfXMLDecoder{dec: xml.NewDecoder(r), nextState: parseXMLTopElem}
+	dec := xml.NewDecoder(r)
+	dec.CharsetReader = charsetReader
+	return &rdfXMLDecoder{dec: dec, nextState: parseXMLTopElem}
+}
+
+// charsetReader is the CharsetReader of the XML decoder. The input is always
+// read as UTF-8, regardless of the encoding declared in the document, so that
+// input in other encodings can be decoded by wrapping the reader with one
+// transcoding to UTF-8, without having to rewrite the XML declaration. Input
+// which is not valid UTF-8 still fails to decode.
+func charsetReader(charset string, input io.Reader) (io.Reader, error) {
+	return input, nil
 }
 
 // SetOption sets a ParseOption to the give value
@@ -101,6 +122,12 @@ func (d *rdfXMLDecoder) SetOption(o ParseOption, v interface{}) error {
 			return fmt.Errorf("ParseOption \"Base\" must be an IRI.")
 		}
 		d.ctx.Base = iri.str
+	case MaxTriples:
+		n, err := maxTriplesOption(v)
+		if err != nil {
+			return err
+		}
+		d.max = n
 	default:
 		return fmt.Errorf("RDF/XML decoder doesn't support option: %v", o)
 	}
@@ -112,6 +139,13 @@ func (d *rdfXMLDecoder) SetOption(o ParseOption, v interface{}) error {
 func (d *rdfXMLDecoder) Decode() (t Triple, err error) {
 	defer d.recover(&err)
 
+	if d.max > 0 && d.n >= d.max {
+		d.limited = true // the limit was lowered
+	}
+	if d.limited {
+		return t, io.EOF
+	}
+
 	if len(d.triples) == 0 {
 		// Run the parser state machine.
 		d.nextXMLToken()
@@ -127,6 +161,10 @@ func (d *rdfXMLDecoder) Decode() (t Triple, err error) {
 
 	t = d.triples[0]
 	d.triples = d.triples[1:]
+	d.n++
+	if d.max > 0 && d.n >= d.max {
+		d.limited = true
+	}
 	return t, err
 }
 
@@ -158,16 +196,6 @@ func parseXMLTopElem(d *rdfXMLDecoder) parseXMLFn {
 		// parsing when we reach the corresponding closing tag.
 		d.topElem = elem.Name.Space + elem.Name.Local
 
-		// Store prefix and namespaces. Go's XML encoder provides the
-		// name space in eachr xml.StartElement, but we need the space
-		// to prefix mapping in case of any parseType="Literal".
-		d.storePrefixNS(elem)
-
-		// Store top-level base
-		if as := attrXML(elem, elBase); as != nil {
-			d.base = as[0].Value
-		}
-
 		// Store top-level prefix and namespaces
 		if as := attrXMLNS(elem); as != nil {
 			for _, a := range as {
@@ -178,9 +206,19 @@ func parseXMLTopElem(d *rdfXMLDecoder) parseXMLFn {
 		if elem.Name.Space != rdfNS || elem.Name.Local != elRDF {
 			// When there is only one top-level node element,
 			// rdf:RDF can be omitted.
+			d.base = d.ctx.Base
 			return parseXMLNodeElem
 		}
 
+		// Store prefix and namespaces. Go's XML encoder provides the
+		// name space in eachr xml.StartElement, but we need the space
+		// to prefix mapping in case of any parseType="Literal".
+		d.storePrefixNS(elem)
+
+		// Store top-level base and lang, to be restored after each top-level node element
+		d.base = d.ctx.Base
+		d.lang = d.ctx.Lang
+
 		d.nextXMLToken()
 		return parseXMLNodeElem
 	default: // xml.Comment, xml.CharData, xml.Directive, xml.ProcInst, xml.EndElement
@@ -221,7 +259,7 @@ func parseXMLNodeElem(d *rdfXMLDecoder) parseXMLFn {
 				}
 
 				if as := attrRDF(elem, elType); as != nil {
-					d.current.Pred = rdfType
+					d.current.Pred = RDFType
 					d.current.Obj = IRI{str: d.resolve(d.ctx.Base, as[0].Value)}
 					d.triples = append(d.triples, d.current)
 
@@ -231,10 +269,6 @@ func parseXMLNodeElem(d *rdfXMLDecoder) parseXMLFn {
 					// TODO what if as := attrRest(elem); as != nil ?
 				}
 
-				if l := attrXML(elem, elLang); l != nil {
-					d.ctx.Lang = l[0].Value
-				}
-
 				if len(elem.Attr) == 0 || d.current.Subj == nil {
 					// A rdf:Description with no ID or about attribute describes an
 					// un-named resource, aka a bNode.
@@ -267,7 +301,6 @@ func parseXMLNodeElem(d *rdfXMLDecoder) parseXMLFn {
 				d.nextXMLToken()
 				return parseXMLPropElem
 			case elBag, elSeq, elAlt:
-				d.storePrefixNS(elem)
 				d.pushContext() // TODO explain why?
 
 				// Handled as typed node element below
@@ -278,6 +311,7 @@ func parseXMLNodeElem(d *rdfXMLDecoder) parseXMLFn {
 				// continue as typed node element below
 			}
 		}
+		d.storePrefixNS(elem)
 		// By here, all cases of rdf:XXX have returned to another state, except
 		// when the element is a typed node element.
 		// http://www.w3.org/TR/rdf-syntax-grammar/#section-Syntax-typed-nodes
@@ -297,7 +331,7 @@ func parseXMLNodeElem(d *rdfXMLDecoder) parseXMLFn {
 			d.bnodeN++
 		}
 
-		d.current.Pred = rdfType
+		d.current.Pred = RDFType
 		d.current.Obj = IRI{elem.Name.Space + elem.Name.Local}
 		d.triples = append(d.triples, d.current)
 
@@ -347,6 +381,7 @@ func parseXMLPropElemOrNodeEnd(d *rdfXMLDecoder) parseXMLFn {
 			// string literal, or a new node element. In either case, store
 			// the relation from current subject as predicate before continuing.
 			d.current.Pred = IRI{str: elem.Name.Space + elem.Name.Local}
+			d.pushContext()
 			d.nextXMLToken()
 
 			return parseXMLCharDataOrElemNode
@@ -360,6 +395,13 @@ func parseXMLPropElemOrNodeEnd(d *rdfXMLDecoder) parseXMLFn {
 		// Restore parent context, if any:
 		d.popContext()
 
+		if d.ctx.Coll != nil {
+			// The node element was an item in a collection;
+			// continue with the next item:
+			d.nextXMLToken()
+			return parseXMLCollItem
+		}
+
 		if d.current.Subj != nil {
 			// Parent context restored, with subject set.
 			// Continue looking for property elements, or the closing
@@ -390,30 +432,9 @@ first:
 		// store it until we know.
 		charData = string(elem)
 	case xml.StartElement:
-		// Entering a new element. We need to push current context to stack:
+		// Entering a new node element. We need to push current context to stack:
 		d.pushContext()
-		d.pushContext() // TODO doc why twice
-
-		if elem.Name.Space == rdfNS {
-			switch elem.Name.Local {
-			case elDescription:
-				// A new element
-				if len(elem.Attr) == 0 {
-					// Element is a blank node
-					d.current.Obj = Blank{id: fmt.Sprintf("_:b%d", d.bnodeN)}
-					d.bnodeN++
-					d.triples = append(d.triples, d.current)
-
-					d.current.Subj = d.current.Obj.(Subject)
-					d.nextState = parseXMLPropElemOrNodeEnd
-					return nil
-				}
-			default:
-				panic(fmt.Errorf("parseXMLCharDataOrElemNode first: TODO rdf:!Decsription"))
-			}
-		} else {
-			panic(fmt.Errorf("parseXMLCharDataOrElemNode first: TODO not rdf name space"))
-		}
+		return d.parseObjNodeElem(elem)
 
 	case xml.EndElement:
 		// It's an empty string literal
@@ -423,6 +444,7 @@ first:
 		d.triples = append(d.triples, d.current)
 
 		d.reifyCheck()
+		d.popContext()
 
 		d.nextState = parseXMLPropElemOrNodeEnd
 		return nil
@@ -439,72 +461,9 @@ second:
 		// A new node element.
 		// (it means that charData was only whitespace between tokens)
 
-		// We need to push stack twice, since popContext is called on the
-		// closing of both property element tag, and node element tag.
+		// Entering a new node element. We need to push current context to stack:
 		d.pushContext()
-		d.pushContext()
-
-		if elem.Name.Space == rdfNS {
-			switch elem.Name.Local {
-			case elDescription:
-				// A new element
-
-				d.storePrefixNS(elem)
-
-				if as := attrRest(elem); as != nil {
-					// Element is an anonymous blank node
-					d.current.Obj = Blank{id: fmt.Sprintf("_:b%d", d.bnodeN)}
-					d.bnodeN++
-					d.triples = append(d.triples, d.current)
-					d.reifyCheck()
-
-					d.current.Subj = d.current.Obj.(Subject)
-
-					// Construct triples from attribute elements
-					for _, a := range as {
-						d.current.Pred = IRI{str: a.Name.Space + a.Name.Local}
-						d.parseObjLiteral(a.Value)
-						d.triples = append(d.triples, d.current)
-					}
-
-					d.nextState = parseXMLPropElemOrNodeEnd
-					return nil
-				}
-
-				if as := attrRDF(elem, elNodeID); as != nil {
-					d.current.Obj = Blank{id: fmt.Sprintf("_:%s", as[0].Value)}
-					d.triples = append(d.triples, d.current)
-					d.reifyCheck()
-
-					d.current.Subj = d.current.Obj.(Subject)
-					d.nextState = parseXMLPropElemOrNodeEnd
-					return nil
-				}
-
-				// Default case, Element is a blank node
-				d.current.Obj = Blank{id: fmt.Sprintf("_:b%d", d.bnodeN)}
-				d.bnodeN++
-				d.triples = append(d.triples, d.current)
-				d.reifyCheck()
-
-				d.current.Subj = d.current.Obj.(Subject)
-				d.nextState = parseXMLPropElemOrNodeEnd
-				return nil
-
-			default:
-				panic(fmt.Errorf("parseXMLCharDataOrElemNode second: TODO rdf:!Description"))
-			}
-		} else {
-			if as := attrRDF(elem, elAbout); as != nil {
-				d.current.Obj = IRI{str: as[0].Value}
-				d.triples = append(d.triples, d.current)
-
-				d.current.Subj = d.current.Obj.(Subject)
-				d.nextState = parseXMLPropElemOrNodeEnd
-				return nil
-			}
-			panic(fmt.Errorf("parseXMLCharDataOrElemNode second: TODO not rdf name space, not rdf:about"))
-		}
+		return d.parseObjNodeElem(elem)
 	case xml.EndElement:
 		// The closing of the property element; it meanst hat charData
 		// represents the string literal as the object.
@@ -520,13 +479,53 @@ second:
 	}
 }
 
+// parseObjNodeElem parses a node element which is the object of the current
+// property element, and establishes it as the subject of its own property elements.
+func (d *rdfXMLDecoder) parseObjNodeElem(elem xml.StartElement) parseXMLFn {
+	typed := true
+	if elem.Name.Space == rdfNS {
+		switch elem.Name.Local {
+		case elDescription:
+			typed = false
+		case elLi, elRDF, elID, elBagID, elAbout, elParseType, elResource, elNodeID, elAboutEach, elAboutEachPrefix:
+			panic(fmt.Errorf("disallowed as node element name: rdf:%s", elem.Name.Local))
+		}
+	}
+
+	d.storePrefixNS(elem)
+
+	d.current.Obj = d.nodeElemSubj(elem).(Object)
+	d.triples = append(d.triples, d.current)
+	d.reifyCheck()
+
+	d.current.Subj = d.current.Obj.(Subject)
+
+	as := attrRest(elem)
+	if typed {
+		d.current.Pred = RDFType
+		d.current.Obj = IRI{str: elem.Name.Space + elem.Name.Local}
+		d.triples = append(d.triples, d.current)
+		as = attrRestWithLn(elem)
+	}
+
+	// Construct triples from attribute elements
+	for _, a := range as {
+		d.current.Pred = IRI{str: a.Name.Space + a.Name.Local}
+		d.parseObjLiteral(a.Value)
+		d.triples = append(d.triples, d.current)
+	}
+
+	d.nextState = parseXMLPropElemOrNodeEnd
+	return nil
+}
+
 // parseXMLPropElemEnd parses the closing tag of a property element. It should
 // only be called when a full triple is ready to be emitted.
 func parseXMLPropElemEnd(d *rdfXMLDecoder) parseXMLFn {
 	switch elem := d.tok.(type) {
 	case xml.EndElement:
 		d.reifyCheck()
-		d.lang = "" // clear the in-scope xml:lang
+		d.popContext()
 
 		return nil
 	case xml.CharData, xml.Comment, xml.ProcInst:
@@ -541,8 +540,6 @@ func parseXMLPropElemEnd(d *rdfXMLDecoder) parseXMLFn {
 func parseXMLPropElem(d *rdfXMLDecoder) parseXMLFn {
 	switch elem := d.tok.(type) {
 	case xml.StartElement:
-		d.storePrefixNS(elem)
-
 		if elem.Name.Space == rdfNS {
 			switch elem.Name.Local {
 			case elLi:
@@ -562,6 +559,11 @@ func parseXMLPropElem(d *rdfXMLDecoder) parseXMLFn {
 			d.current.Pred = IRI{str: elem.Name.Space + elem.Name.Local}
 		}
 
+		// Enter the property element; the context of the containing
+		// node element is restored when the property element is closed.
+		d.pushContext()
+		d.storePrefixNS(elem)
+
 		if a := attrRDF(elem, elID); a != nil {
 			// Store ID to be used to create the IRI for reified statements (in parseXMLPropElemEnd)
 			d.reifyID = "#" + a[0].Value
@@ -578,7 +580,6 @@ func parseXMLPropElem(d *rdfXMLDecoder) parseXMLFn {
 				d.triples = append(d.triples, d.current)
 				d.reifyCheck()
 
-				d.pushContext()
 				d.current.Subj = d.current.Obj.(Subject)
 				d.nextXMLToken()
 				return parseXMLPropElemOrNodeEnd
@@ -600,6 +601,8 @@ func parseXMLPropElem(d *rdfXMLDecoder) parseXMLFn {
 				// The inner tokens and character data are stored as an XML literal
 				d.parseXMLLiteral(elem)
 				d.triples = append(d.triples, d.current)
+				d.reifyCheck()
+				d.popContext()
 
 				d.nextState = parseXMLPropElemOrNodeEnd
 				return nil
@@ -641,20 +644,13 @@ func parseXMLPropElem(d *rdfXMLDecoder) parseXMLFn {
 			d.triples = append(d.triples, d.current)
 			d.reifyCheck()
 
-			d.pushContext()
 			d.nextState = parseXMLPropElemOrNodeEnd
 			return nil
 		}
 
 		if a := attrRDF(elem, elDataType); a != nil {
+			// A datatype takes precedence over any in-scope xml:lang.
 			d.dt = &IRI{str: d.resolve(d.ctx.Base, a[0].Value)}
-		} else {
-			// Only check for xml:lang if datatype not found
-			// TODO or error if both?
-			if l := attrXML(elem, elLang); l != nil {
-				// store as in-scope lang
-				d.lang = l[0].Value
-			}
 		}
 
 		if as := attrRest(elem); as != nil {
@@ -668,7 +664,6 @@ func parseXMLPropElem(d *rdfXMLDecoder) parseXMLFn {
 			d.current.Obj = Blank{id: fmt.Sprintf("_:b%d", d.bnodeN)}
 			d.bnodeN++
 			d.triples = append(d.triples, d.current)
-			d.pushContext()
 
 			// We need to reify before we change predicate & object
 			d.reifyCheck()
@@ -697,74 +692,102 @@ func parseXMLPropElem(d *rdfXMLDecoder) parseXMLFn {
 	}
 }
 
-// parseXMLColl parses collections; all the node elements in a property
-// element with attribute parseType="Collection".
-// Subject an Predicate is set.
+// parseXMLColl parses the start of a property element with attribute
+// parseType="Collection". Subject and Predicate is set.
+// http://www.w3.org/TR/rdf-syntax-grammar/#section-Syntax-parsetype-Collection
 func parseXMLColl(d *rdfXMLDecoder) parseXMLFn {
-	d.current.Obj = Blank{id: fmt.Sprintf("_:b%d", d.bnodeN)}
-	d.bnodeN++
+	d.ctx.Coll = &xmlColl{subj: d.current.Subj, pred: d.current.Pred}
+	d.nextXMLToken()
+	return parseXMLCollItem
+}
 
-	d.triples = append(d.triples, d.current)
+// parseXMLCollItem parses the node elements of a collection, one at a time,
+// linking them together as a rdf:List. Each node element is parsed as any other
+// node element, returning here when it is closed.
+func parseXMLCollItem(d *rdfXMLDecoder) parseXMLFn {
+	switch elem := d.tok.(type) {
+	case xml.StartElement:
+		c := d.ctx.Coll
+		node := Blank{id: fmt.Sprintf("_:b%d", d.bnodeN)}
+		d.bnodeN++
+		if c.last.id == "" {
+			d.triples = append(d.triples, Triple{Subj: c.subj, Pred: c.pred, Obj: node})
+		} else {
+			d.triples = append(d.triples, Triple{Subj: c.last, Pred: rdfRest, Obj: node})
+		}
+		c.last = node
 
-	d.current.Subj = d.current.Obj.(Subject)
-	tag := d.tok.(xml.StartElement).Name.Space + d.tok.(xml.StartElement).Name.Local
-	first := true
-outer:
-	for {
-		d.nextXMLToken()
+		// Enter the node element; the context is restored when it's closed.
+		d.pushContext()
+		d.ctx.Coll = nil
+		d.storePrefixNS(elem)
 
-		switch elem := d.tok.(type) {
-		case xml.StartElement:
-			if elem.Name.Space == rdfNS && elem.Name.Local == elDescription {
-				if a := attrRDF(elem, elAbout); a != nil {
-					if first {
-						d.current.Pred = rdfFirst
-						d.current.Obj = IRI{str: a[0].Value}
-						d.triples = append(d.triples, d.current)
-						first = false
-					} else {
-						d.current.Pred = rdfRest
-						d.current.Obj = Blank{id: fmt.Sprintf("_:b%d", d.bnodeN)}
-						d.bnodeN++
-						d.triples = append(d.triples, d.current)
+		d.current.Subj = d.nodeElemSubj(elem)
+		d.triples = append(d.triples, Triple{Subj: node, Pred: rdfFirst, Obj: d.current.Subj.(Object)})
 
-						d.current.Subj = d.current.Obj.(Subject)
-						d.current.Pred = rdfFirst
-						d.current.Obj = IRI{str: a[0].Value}
-						d.triples = append(d.triples, d.current)
-					}
-				} else {
-					panic(fmt.Errorf("parseXMLColl: TODO <rdf:Description> element without rdf:about %v", elem))
-				}
-			} else {
-				panic(fmt.Errorf("parseXMLColl: TODO element node not <rdf:Description> %v", elem))
-			}
-		case xml.EndElement:
-			if elem.Name.Space+elem.Name.Local == tag {
-				// we're done
-				break outer
-			}
-		default: // xml.CharData, xml.Comment etc
-			continue outer
+		as := attrRest(elem)
+		if elem.Name.Space != rdfNS || elem.Name.Local != elDescription {
+			// Typed node element
+			d.current.Pred = RDFType
+			d.current.Obj = IRI{str: elem.Name.Space + elem.Name.Local}
+			d.triples = append(d.triples, d.current)
+			as = attrRestWithLn(elem)
+		}
+		for _, a := range as {
+			d.current.Pred = IRI{str: a.Name.Space + a.Name.Local}
+			d.parseObjLiteral(a.Value)
+			d.triples = append(d.triples, d.current)
 		}
-	}
 
-	// add final statement marking the end of the collection
-	d.current.Pred = rdfRest
-	d.current.Obj = rdfNil
-	d.triples = append(d.triples, d.current)
+		d.nextState = parseXMLPropElemOrNodeEnd
+		return nil
+	case xml.EndElement:
+		// The closing of the property element; terminate the list.
+		c := d.ctx.Coll
+		if c.last.id == "" {
+			d.triples = append(d.triples, Triple{Subj: c.subj, Pred: c.pred, Obj: RDFNil})
+		} else {
+			d.triples = append(d.triples, Triple{Subj: c.last, Pred: rdfRest, Obj: RDFNil})
+		}
+		d.popContext()
+		d.nextState = parseXMLPropElemOrNodeEnd
+		return nil
+	default: // xml.CharData, xml.Comment etc
+		d.nextXMLToken()
+		return parseXMLCollItem
+	}
+}
 
-	return nil
+// nodeElemSubj returns the subject described by a node element; either
+// a IRI given by rdf:about or rdf:ID, a named blank node given by rdf:nodeID,
+// or else a new anonymous blank node.
+func (d *rdfXMLDecoder) nodeElemSubj(elem xml.StartElement) Subject {
+	if as := attrRDF(elem, elAbout); as != nil {
+		if a := attrRDF(elem, elNodeID); a != nil {
+			panic(errors.New("A node element cannot have both rdf:about and rdf:nodeID"))
+		}
+		return IRI{str: d.resolve(d.ctx.Base, as[0].Value)}
+	}
+	if as := attrRDF(elem, elID); as != nil {
+		if a := attrRDF(elem, elNodeID); a != nil {
+			panic(errors.New("A node element cannot have both rdf:ID and rdf:nodeID"))
+		}
+		return IRI{str: d.resolve(d.ctx.Base, "#"+as[0].Value)}
+	}
+	if as := attrRDF(elem, elNodeID); as != nil {
+		return Blank{id: fmt.Sprintf("_:%s", as[0].Value)}
+	}
+	b := Blank{id: fmt.Sprintf("_:b%d", d.bnodeN)}
+	d.bnodeN++
+	return b
 }
 
 // parseObjLiteral parses the object from the given character data,
 // making sure it get's the in-scope xml:lang and correct datatype.
 func (d *rdfXMLDecoder) parseObjLiteral(data string) {
 	if d.dt != nil {
-		d.current.Obj = Literal{str: data, DataType: *d.dt, lang: d.lang}
+		d.current.Obj = Literal{str: data, DataType: *d.dt}
 		d.dt = nil
-	} else if d.lang != "" {
-		d.current.Obj = Literal{str: data, DataType: rdfLangString, lang: d.lang}
 	} else if d.ctx.Lang != "" {
 		d.current.Obj = Literal{str: data, DataType: rdfLangString, lang: d.ctx.Lang}
 	} else {
@@ -800,6 +823,11 @@ parseLiteral:
 				b.WriteString(elem.Name.Local)
 			}
 			for _, a := range elem.Attr {
+				if a.Name.Space == elXMLNS {
+					// Name space declarations are written along with
+					// the elements and attributes using them.
+					continue
+				}
 				b.Write([]byte(" "))
 				if a.Name.Space != "" {
 					b.WriteString(d.getPrefix(a.Name.Space))
@@ -852,7 +880,7 @@ func (d *rdfXMLDecoder) reifyCheck() {
 		d.triples = append(d.triples,
 			Triple{
 				Subj: iri,
-				Pred: rdfType,
+				Pred: RDFType,
 				Obj:  rdfStatement,
 			},
 			Triple{
@@ -913,8 +941,9 @@ func (d *rdfXMLDecoder) getNS(prefix string) string {
 }
 
 // storePrefixNS stores any name space prefixes declared to the element context.
-// It also stores the base URI, if xml:base is present.
-// TODO also store xml:lang?
+// It also stores the base URI, if xml:base is present, and the language, if
+// xml:lang is present. Both are inherited by all descendants of the element,
+// until overridden. An empty xml:lang resets the language.
 func (d *rdfXMLDecoder) storePrefixNS(elem xml.StartElement) {
 	if as := attrXMLNS(elem); as != nil {
 		for _, a := range as {
@@ -922,7 +951,15 @@ func (d *rdfXMLDecoder) storePrefixNS(elem xml.StartElement) {
 		}
 	}
 	if as := attrXML(elem, elBase); as != nil {
-		d.ctx.Base = as[0].Value
+		base := as[0].Value
+		if i := strings.IndexAny(base, ":/?#"); i == -1 || base[i] != ':' {
+			// A relative IRI is resolved against the in-scope base.
+			base = d.resolve(d.ctx.Base, base)
+		}
+		d.ctx.Base = base
+	}
+	if as := attrXML(elem, elLang); as != nil {
+		d.ctx.Lang = as[0].Value
 	}
 }
 
@@ -946,6 +983,7 @@ func (d *rdfXMLDecoder) popContext() {
 		d.ctx = evalCtx{}
 		d.current.Subj = nil
 		d.ctx.Base = d.base
+		d.ctx.Lang = d.lang
 	case 1:
 		d.ctx = d.ctxStack[0]
 		d.current.Subj = d.ctxStack[0].Subj
@@ -967,7 +1005,7 @@ func (d *rdfXMLDecoder) recover(errp *error) {
 			panic(e)
 		}
 		//d.stop() something to clean up?
-		*errp = e.(error)
+		*errp = formatErr(e.(error), RDFXML)
 	}
 	return
 }
@@ -976,6 +1014,10 @@ func (d *rdfXMLDecoder) nextXMLToken() {
 	var err error
 	d.tok, err = d.dec.Token()
 	if err != nil {
+		if se, ok := err.(*xml.SyntaxError); ok && se.Msg == "unexpected EOF" {
+			// The document ended before all elements were closed.
+			panic(&ParseError{Line: se.Line, Err: fmt.Errorf("%w: XML element not closed", ErrUnexpectedEOF)})
+		}
 		panic(err)
 	}
 }
@@ -996,129 +1038,7 @@ func (d *rdfXMLDecoder) resolve(base string, path string) string {
 			break
 		}
 	}
-	if len(base) == 0 {
-		return path
-	}
-	if len(path) == 0 {
-		return base[:iriFragmentIdx(base)]
-	}
-	switch path[0] {
-	case '#':
-		return base[:iriFragmentIdx(base)] + path
-	case '/':
-		if len(path) > 1 && path[1] == '/' {
-			return base[:iriSchemeEnd(base)] + path
-		}
-		return base[:iriHostEnd(base)] + path
-	case '.':
-		numLevels := len(strings.Split(path, "../"))
-		return base[:iriSlashIdx(base, numLevels)] + strings.TrimLeft(path, "../")
-	default:
-		i := iriLastSlashIdx(base)
-		if base[i-1] != '/' {
-			return base + "/" + path
-		}
-		return base[:i] + path
-	}
-}
-
-func iriFragmentIdx(s string) int {
-	i := len(s)
-	for i > 0 {
-		r, w := utf8.DecodeLastRuneInString(s[0:i])
-		i -= w
-		if r == '#' {
-			return i
-		}
-		if r == '/' {
-			break
-		}
-	}
-	return len(s)
-}
-
-func iriHostEnd(s string) int {
-	i := 0
-	l := len(s)
-	for i < l {
-		r, w := utf8.DecodeRuneInString(s[i:])
-		if w == 0 {
-			return i
-		}
-		i += w
-		if r == '.' {
-			for r, w = utf8.DecodeRuneInString(s[i:]); isAlpha(r); r, w = utf8.DecodeRuneInString(s[i:]) {
-				i += w
-			}
-			if w == 0 {
-				return i
-			}
-			if r == '/' {
-				return i
-			}
-		}
-	}
-	return i
-}
-
-func iriSchemeEnd(s string) int {
-	if strings.HasPrefix(s, "http://") {
-		return 5
-	}
-	i := 0
-	l := len(s)
-	for i < l {
-		r, w := utf8.DecodeRuneInString(s[i:])
-		if w == 0 {
-			return i
-		}
-		i += w
-		if r == ':' {
-			if i+2 < l && s[i] == '/' && s[i+1] == '/' {
-				return i
-			}
-		}
-	}
-	return i
-}
-
-func iriLastSlashIdx(s string) int {
-	i := len(s)
-	for i > 0 {
-		r, w := utf8.DecodeLastRuneInString(s[0:i])
-		if r == '/' {
-			if i > 1 && s[i-w-1] == '/' {
-				return len(s)
-			}
-			return i
-		}
-		i -= w
-	}
-	return 0
-}
-
-func iriSlashIdx(s string, n int) int {
-	c := 0
-	i := len(s)
-	for {
-		r, w := utf8.DecodeLastRuneInString(s[:i])
-		if w == 0 {
-			break
-		}
-		i -= w
-
-		if r == '/' {
-			if i > 0 && s[i-1] == '/' {
-				// Return if we have reached the URI scheme.
-				return i + 1
-			}
-			c++
-			if c == n {
-				return i + 1
-			}
-		}
-	}
-	return i
+	return ResolveIRI(IRI{str: base}, path).str
 }
 
 // isLn checks if string matches ^_[1-9]\d*$
@@ -3913,3 +3913,323 @@ _:b1 <http://example.org/property2> _:a .
 		"",
 	},
 }
+
+// rdfxmlToNT decodes the RDF/XML document, resolving against the base
+// http://example.org/doc, and returns the triples serialized as N-Triples.
+func rdfxmlToNT(t *testing.T, rdfxml string) string {
+	dec := NewTripleDecoder(bytes.NewBufferString(rdfxml), RDFXML)
+	dec.SetOption(Base, IRI{str: "http://example.org/doc"})
+	ts, err := dec.DecodeAll()
+	if err != nil {
+		t.Fatalf("decoding %s failed: %v", rdfxml, err)
+	}
+	var b bytes.Buffer
+	enc := NewTripleEncoder(&b, NTriples)
+	if err := enc.EncodeAll(ts); err != nil {
+		t.Fatal(err)
+	}
+	enc.Close()
+	return b.String()
+}
+
+func TestRDFXMLParseType(t *testing.T) {
+	tests := []struct {
+		rdfxml string
+		nt     string
+	}{
+		{
+			// Collection with different kinds of node elements
+			`<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns:ex="http://example.org/">
+  <rdf:Description rdf:about="http://example.org/basket">
+    <ex:hasFruit rdf:parseType="Collection">
+      <rdf:Description rdf:about="banana"/>
+      <ex:Apple rdf:about="http://example.org/apple"/>
+      <rdf:Description rdf:nodeID="pear"/>
+      <rdf:Description>
+        <ex:name>kiwi</ex:name>
+      </rdf:Description>
+    </ex:hasFruit>
+    <ex:empty rdf:parseType="Collection"></ex:empty>
+  </rdf:Description>
+</rdf:RDF>`,
+			`<http://example.org/basket> <http://example.org/hasFruit> _:b0 .
+_:b0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#first> <http://example.org/banana> .
+_:b0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#rest> _:b1 .
+_:b1 <http://www.w3.org/1999/02/22-rdf-syntax-ns#first> <http://example.org/apple> .
+<http://example.org/apple> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://example.org/Apple> .
+_:b1 <http://www.w3.org/1999/02/22-rdf-syntax-ns#rest> _:b2 .
+_:b2 <http://www.w3.org/1999/02/22-rdf-syntax-ns#first> _:pear .
+_:b2 <http://www.w3.org/1999/02/22-rdf-syntax-ns#rest> _:b3 .
+_:b3 <http://www.w3.org/1999/02/22-rdf-syntax-ns#first> _:b4 .
+_:b4 <http://example.org/name> "kiwi" .
+_:b3 <http://www.w3.org/1999/02/22-rdf-syntax-ns#rest> <http://www.w3.org/1999/02/22-rdf-syntax-ns#nil> .
+<http://example.org/basket> <http://example.org/empty> <http://www.w3.org/1999/02/22-rdf-syntax-ns#nil> .
+`,
+		},
+		{
+			// Collection of nested typed node elements, as written by ontology editors
+			`<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns:owl="http://www.w3.org/2002/07/owl#">
+  <owl:Class rdf:about="#Parent">
+    <owl:equivalentClass>
+      <owl:Class>
+        <owl:intersectionOf rdf:parseType="Collection">
+          <rdf:Description rdf:about="#Person"/>
+          <owl:Restriction>
+            <owl:onProperty rdf:resource="#hasChild"/>
+            <owl:someValuesFrom>
+              <owl:Class rdf:about="#Person"/>
+            </owl:someValuesFrom>
+          </owl:Restriction>
+        </owl:intersectionOf>
+      </owl:Class>
+    </owl:equivalentClass>
+  </owl:Class>
+</rdf:RDF>`,
+			`<http://example.org/doc#Parent> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://www.w3.org/2002/07/owl#Class> .
+<http://example.org/doc#Parent> <http://www.w3.org/2002/07/owl#equivalentClass> _:b0 .
+_:b0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://www.w3.org/2002/07/owl#Class> .
+_:b0 <http://www.w3.org/2002/07/owl#intersectionOf> _:b1 .
+_:b1 <http://www.w3.org/1999/02/22-rdf-syntax-ns#first> <http://example.org/doc#Person> .
+_:b1 <http://www.w3.org/1999/02/22-rdf-syntax-ns#rest> _:b2 .
+_:b2 <http://www.w3.org/1999/02/22-rdf-syntax-ns#first> _:b3 .
+_:b3 <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://www.w3.org/2002/07/owl#Restriction> .
+_:b3 <http://www.w3.org/2002/07/owl#onProperty> <http://example.org/doc#hasChild> .
+_:b3 <http://www.w3.org/2002/07/owl#someValuesFrom> <http://example.org/doc#Person> .
+<http://example.org/doc#Person> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://www.w3.org/2002/07/owl#Class> .
+_:b2 <http://www.w3.org/1999/02/22-rdf-syntax-ns#rest> <http://www.w3.org/1999/02/22-rdf-syntax-ns#nil> .
+`,
+		},
+		{
+			// Resource, nested and empty
+			`<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns:ex="http://example.org/">
+  <rdf:Description rdf:about="http://example.org/s">
+    <ex:res rdf:parseType="Resource">
+      <ex:inner rdf:parseType="Resource"><ex:v>deep</ex:v></ex:inner>
+      <ex:w rdf:resource="w"/>
+    </ex:res>
+    <ex:empty rdf:parseType="Resource"/>
+    <ex:after>ok</ex:after>
+  </rdf:Description>
+</rdf:RDF>`,
+			`<http://example.org/s> <http://example.org/res> _:b0 .
+_:b0 <http://example.org/inner> _:b1 .
+_:b1 <http://example.org/v> "deep" .
+_:b0 <http://example.org/w> <http://example.org/w> .
+<http://example.org/s> <http://example.org/empty> _:b2 .
+<http://example.org/s> <http://example.org/after> "ok" .
+`,
+		},
+		{
+			// Literal, with mixed content and name space declarations
+			`<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns:ex="http://example.org/">
+  <rdf:Description rdf:about="http://example.org/s">
+    <ex:lit rdf:parseType="Literal"><b>bold</b> text</ex:lit>
+    <ex:ns rdf:parseType="Literal"><ex:b xmlns:ex="http://example.org/">x</ex:b></ex:ns>
+  </rdf:Description>
+</rdf:RDF>`,
+			`<http://example.org/s> <http://example.org/lit> "<b>bold</b> text"^^<http://www.w3.org/1999/02/22-rdf-syntax-ns#XMLLiteral> .
+<http://example.org/s> <http://example.org/ns> "<ex:b xmlns:ex=\"http://example.org/\">x</ex:b>"^^<http://www.w3.org/1999/02/22-rdf-syntax-ns#XMLLiteral> .
+`,
+		},
+	}
+
+	for _, test := range tests {
+		if got := rdfxmlToNT(t, test.rdfxml); got != test.nt {
+			t.Errorf("decoding %s =>\n%s\nwant:\n%s", test.rdfxml, got, test.nt)
+		}
+	}
+}
+
+func TestRDFXMLBase(t *testing.T) {
+	tests := []struct {
+		rdfxml string
+		nt     string
+	}{
+		{
+			// rdf:ID and rdf:about against the document base, which
+			// must be kept for every top-level node element
+			`<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns:ex="http://example.org/">
+  <rdf:Description rdf:ID="a">
+    <ex:p rdf:resource="#b"/>
+  </rdf:Description>
+  <ex:Thing rdf:about="c">
+    <ex:p rdf:resource=""/>
+  </ex:Thing>
+  <rdf:Description rdf:ID="d" ex:q="x"/>
+</rdf:RDF>`,
+			`<http://example.org/doc#a> <http://example.org/p> <http://example.org/doc#b> .
+<http://example.org/c> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://example.org/Thing> .
+<http://example.org/c> <http://example.org/p> <http://example.org/doc> .
+<http://example.org/doc#d> <http://example.org/q> "x" .
+`,
+		},
+		{
+			// xml:base scoping: on typed node elements, on property elements,
+			// relative xml:base, and nested node elements
+			`<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns:ex="http://example.org/" xml:base="http://example.org/dir/">
+  <ex:Thing rdf:ID="t1" xml:base="http://other.org/x/">
+    <ex:p rdf:resource="rel"/>
+  </ex:Thing>
+  <rdf:Description rdf:ID="d1">
+    <ex:p xml:base="http://third.org/" rdf:resource="a"/>
+    <ex:q rdf:resource="b"/>
+    <ex:r rdf:ID="st" rdf:resource="c"/>
+  </rdf:Description>
+  <rdf:Description rdf:about="x" xml:base="sub/">
+    <ex:p rdf:resource="y"/>
+  </rdf:Description>
+  <rdf:Description rdf:about="z">
+    <ex:n>
+      <rdf:Description rdf:ID="inner" xml:base="http://nested.org/">
+         <ex:m rdf:resource="m"/>
+      </rdf:Description>
+    </ex:n>
+    <ex:o rdf:resource="o"/>
+  </rdf:Description>
+</rdf:RDF>`,
+			`<http://other.org/x/#t1> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://example.org/Thing> .
+<http://other.org/x/#t1> <http://example.org/p> <http://other.org/x/rel> .
+<http://example.org/dir/#d1> <http://example.org/p> <http://third.org/a> .
+<http://example.org/dir/#d1> <http://example.org/q> <http://example.org/dir/b> .
+<http://example.org/dir/#d1> <http://example.org/r> <http://example.org/dir/c> .
+<http://example.org/dir/#st> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://www.w3.org/1999/02/22-rdf-syntax-ns#Statement> .
+<http://example.org/dir/#st> <http://www.w3.org/1999/02/22-rdf-syntax-ns#subject> <http://example.org/dir/#d1> .
+<http://example.org/dir/#st> <http://www.w3.org/1999/02/22-rdf-syntax-ns#predicate> <http://example.org/r> .
+<http://example.org/dir/#st> <http://www.w3.org/1999/02/22-rdf-syntax-ns#object> <http://example.org/dir/c> .
+<http://example.org/dir/sub/x> <http://example.org/p> <http://example.org/dir/sub/y> .
+<http://example.org/dir/z> <http://example.org/n> <http://nested.org/#inner> .
+<http://nested.org/#inner> <http://example.org/m> <http://nested.org/m> .
+<http://example.org/dir/z> <http://example.org/o> <http://example.org/dir/o> .
+`,
+		},
+	}
+
+	for _, test := range tests {
+		if got := rdfxmlToNT(t, test.rdfxml); got != test.nt {
+			t.Errorf("decoding %s =>\n%s\nwant:\n%s", test.rdfxml, got, test.nt)
+		}
+	}
+}
+
+func TestRDFXMLLang(t *testing.T) {
+	// xml:lang is inherited by descendant elements until overridden,
+	// or reset by xml:lang="".
+	input := `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns:ex="http://example.org/" xml:lang="en">
+  <rdf:Description rdf:about="http://example.org/a" ex:attr="top">
+    <ex:title>Top</ex:title>
+    <ex:title xml:lang="de">Oben</ex:title>
+    <ex:after>Still en</ex:after>
+    <ex:child>
+      <ex:Thing xml:lang="fr" ex:attr="fr attr">
+        <ex:title>Haut</ex:title>
+        <ex:reset xml:lang="">plain</ex:reset>
+        <ex:res rdf:parseType="Resource" xml:lang="">
+          <ex:title>plain too</ex:title>
+          <ex:nb xml:lang="nb">topp</ex:nb>
+        </ex:res>
+        <ex:n rdf:datatype="http://www.w3.org/2001/XMLSchema#integer">1</ex:n>
+      </ex:Thing>
+    </ex:child>
+    <ex:back>en again</ex:back>
+  </rdf:Description>
+  <rdf:Description rdf:about="http://example.org/b" xml:lang="">
+    <ex:title>none</ex:title>
+  </rdf:Description>
+  <rdf:Description rdf:about="http://example.org/c">
+    <ex:title>en</ex:title>
+  </rdf:Description>
+</rdf:RDF>`
+	want := `<http://example.org/a> <http://example.org/attr> "top"@en .
+<http://example.org/a> <http://example.org/title> "Top"@en .
+<http://example.org/a> <http://example.org/title> "Oben"@de .
+<http://example.org/a> <http://example.org/after> "Still en"@en .
+<http://example.org/a> <http://example.org/child> _:b0 .
+_:b0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://example.org/Thing> .
+_:b0 <http://example.org/attr> "fr attr"@fr .
+_:b0 <http://example.org/title> "Haut"@fr .
+_:b0 <http://example.org/reset> "plain" .
+_:b0 <http://example.org/res> _:b1 .
+_:b1 <http://example.org/title> "plain too" .
+_:b1 <http://example.org/nb> "topp"@nb .
+_:b0 <http://example.org/n> "1"^^<http://www.w3.org/2001/XMLSchema#integer> .
+<http://example.org/a> <http://example.org/back> "en again"@en .
+<http://example.org/b> <http://example.org/title> "none" .
+<http://example.org/c> <http://example.org/title> "en"@en .
+`
+	if got := rdfxmlToNT(t, input); got != want {
+		t.Errorf("decoding %s =>\n%s\nwant:\n%s", input, got, want)
+	}
+}
+
+func TestRDFXMLContainers(t *testing.T) {
+	// rdf:li is numbered per containing element, also when containers are
+	// nested inside the members of other containers.
+	input := `<?xml version="1.0"?>
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+         xmlns:dc="http://purl.org/dc/elements/1.1/">
+  <rdf:Description rdf:about="http://example.org/book">
+    <dc:creator>
+      <rdf:Seq>
+        <rdf:li>Alice</rdf:li>
+        <rdf:li>
+          <rdf:Description rdf:about="http://example.org/bob">
+            <dc:title>
+              <rdf:Bag>
+                <rdf:li>x</rdf:li>
+                <rdf:li>y</rdf:li>
+              </rdf:Bag>
+            </dc:title>
+          </rdf:Description>
+        </rdf:li>
+        <rdf:li rdf:resource="http://example.org/carol"/>
+        <rdf:li xml:lang="en">Dave</rdf:li>
+      </rdf:Seq>
+    </dc:creator>
+    <dc:subject>
+      <rdf:Alt>
+        <rdf:li>one</rdf:li>
+      </rdf:Alt>
+    </dc:subject>
+  </rdf:Description>
+  <rdf:Bag rdf:about="http://example.org/b2"><rdf:li>z</rdf:li></rdf:Bag>
+</rdf:RDF>`
+	want := `<http://example.org/book> <http://purl.org/dc/elements/1.1/creator> _:b0 .
+_:b0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://www.w3.org/1999/02/22-rdf-syntax-ns#Seq> .
+_:b0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#_1> "Alice" .
+_:b0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#_2> <http://example.org/bob> .
+<http://example.org/bob> <http://purl.org/dc/elements/1.1/title> _:b1 .
+_:b1 <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://www.w3.org/1999/02/22-rdf-syntax-ns#Bag> .
+_:b1 <http://www.w3.org/1999/02/22-rdf-syntax-ns#_1> "x" .
+_:b1 <http://www.w3.org/1999/02/22-rdf-syntax-ns#_2> "y" .
+_:b0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#_3> <http://example.org/carol> .
+_:b0 <http://www.w3.org/1999/02/22-rdf-syntax-ns#_4> "Dave"@en .
+<http://example.org/book> <http://purl.org/dc/elements/1.1/subject> _:b2 .
+_:b2 <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://www.w3.org/1999/02/22-rdf-syntax-ns#Alt> .
+_:b2 <http://www.w3.org/1999/02/22-rdf-syntax-ns#_1> "one" .
+<http://example.org/b2> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://www.w3.org/1999/02/22-rdf-syntax-ns#Bag> .
+<http://example.org/b2> <http://www.w3.org/1999/02/22-rdf-syntax-ns#_1> "z" .
+`
+	if got := rdfxmlToNT(t, input); got != want {
+		t.Errorf("decoding %s =>\n%s\nwant:\n%s", input, got, want)
+	}
+}
+
+func TestRDFXMLSameDocumentReferences(t *testing.T) {
+	input := `<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns:ex="http://example.org/ns#"
+         xml:base="http://example.org/dir/doc#frag">
+  <rdf:Description rdf:about="">
+    <ex:p rdf:resource="#foo"/>
+    <ex:p rdf:resource="?q"/>
+  </rdf:Description>
+  <rdf:Description rdf:ID="bar">
+    <ex:p rdf:resource=""/>
+  </rdf:Description>
+</rdf:RDF>`
+	want := `<http://example.org/dir/doc> <http://example.org/ns#p> <http://example.org/dir/doc#foo> .
+<http://example.org/dir/doc> <http://example.org/ns#p> <http://example.org/dir/doc?q> .
+<http://example.org/dir/doc#bar> <http://example.org/ns#p> <http://example.org/dir/doc> .
+`
+	if got := rdfxmlToNT(t, input); got != want {
+		t.Errorf("decoding %s =>\n%s\nwant:\n%s", input, got, want)
+	}
+}
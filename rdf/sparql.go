@@ -0,0 +1,131 @@
+package rdf
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// sparqlResults represents the SPARQL 1.1 Query Results JSON Format:
+// http://www.w3.org/TR/sparql11-results-json/
+type sparqlResults struct {
+	Head struct {
+		Vars []string `json:"vars"`
+	} `json:"head"`
+	Results struct {
+		Bindings []map[string]sparqlBinding `json:"bindings"`
+	} `json:"results"`
+}
+
+type sparqlBinding struct {
+	Type     string `json:"type"` // uri, literal, typed-literal or bnode
+	Value    string `json:"value"`
+	Lang     string `json:"xml:lang"`
+	DataType string `json:"datatype"`
+}
+
+// DecodeSPARQLJSONAsTriples reads SPARQL SELECT results in the JSON format,
+// and returns one triple per result row, taking the subject, predicate and
+// object from the variables with the given names (without the leading '?').
+//
+// Rows where any of the three variables are unbound are skipped. It is an
+// error if a variable is bound to a term not valid in its position, for
+// example a literal as subject.
+func DecodeSPARQLJSONAsTriples(r io.Reader, sVar, pVar, oVar string) ([]Triple, error) {
+	var res sparqlResults
+	if err := json.NewDecoder(r).Decode(&res); err != nil {
+		return nil, err
+	}
+
+	var ts []Triple
+	for i, row := range res.Results.Bindings {
+		s, sok := row[sVar]
+		p, pok := row[pVar]
+		o, ook := row[oVar]
+		if !sok || !pok || !ook {
+			continue
+		}
+
+		subj, err := s.term()
+		if err != nil {
+			return nil, fmt.Errorf("result %d: ?%s: %v", i, sVar, err)
+		}
+		pred, err := p.term()
+		if err != nil {
+			return nil, fmt.Errorf("result %d: ?%s: %v", i, pVar, err)
+		}
+		obj, err := o.term()
+		if err != nil {
+			return nil, fmt.Errorf("result %d: ?%s: %v", i, oVar, err)
+		}
+
+		var t Triple
+		var ok bool
+		if t.Subj, ok = subj.(Subject); !ok {
+			return nil, fmt.Errorf("result %d: ?%s: %s not valid as subject", i, sVar, s.Type)
+		}
+		if t.Pred, ok = pred.(Predicate); !ok {
+			return nil, fmt.Errorf("result %d: ?%s: %s not valid as predicate", i, pVar, p.Type)
+		}
+		t.Obj = obj.(Object)
+		ts = append(ts, t)
+	}
+	return ts, nil
+}
+
+// term converts the binding to a RDF term.
+func (b sparqlBinding) term() (Term, error) {
+	switch b.Type {
+	case "uri":
+		iri, err := NewIRI(b.Value)
+		if err != nil {
+			return nil, err
+		}
+		return iri, nil
+	case "bnode":
+		blank, err := NewBlank(b.Value)
+		if err != nil {
+			return nil, err
+		}
+		return blank, nil
+	case "literal", "typed-literal":
+		if b.Lang != "" {
+			if err := checkLangTag(b.Lang); err != nil {
+				return nil, err
+			}
+			return Literal{str: b.Value, lang: b.Lang, DataType: rdfLangString}, nil
+		}
+		if b.DataType != "" {
+			dt, err := NewIRI(b.DataType)
+			if err != nil {
+				return nil, err
+			}
+			return NewTypedLiteral(b.Value, dt), nil
+		}
+		return Literal{str: b.Value, DataType: xsdString}, nil
+	default:
+		return nil, fmt.Errorf("unknown term type: %q", b.Type)
+	}
+}
+
+// checkLangTag returns an error unless the tag is well-formed as a BCP 47
+// language tag: subtags of 1 to 8 letters or digits, separated by '-', where
+// the first subtag has letters only. Unlike NewLangLiteral, it allows any
+// number of subtags, e.g. "zh-Hant-HK" or "en-GB-oxendict".
+func checkLangTag(tag string) error {
+	for i, sub := range strings.Split(tag, "-") {
+		if len(sub) == 0 || len(sub) > 8 {
+			return fmt.Errorf("invalid language tag %q: subtags must have 1 to 8 characters", tag)
+		}
+		for _, r := range sub {
+			switch {
+			case (r >= 'A' && r <= 'Z') || (r >= 'a' && r <= 'z'):
+			case r >= '0' && r <= '9' && i > 0:
+			default:
+				return fmt.Errorf("invalid language tag %q: unexpected character: %q", tag, r)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package rdf
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDecodeSPARQLJSONAsTriples(t *testing.T) {
+	input := `{
+  "head": {"vars": ["s", "p", "o", "x"]},
+  "results": {
+    "bindings": [
+      {
+        "s": {"type": "uri", "value": "http://example.org/a"},
+        "p": {"type": "uri", "value": "http://example.org/p"},
+        "o": {"type": "literal", "value": "hei", "xml:lang": "no"}
+      },
+      {
+        "s": {"type": "bnode", "value": "b0"},
+        "p": {"type": "uri", "value": "http://example.org/p"},
+        "o": {"type": "literal", "value": "1", "datatype": "http://www.w3.org/2001/XMLSchema#integer"},
+        "x": {"type": "literal", "value": "ignored"}
+      },
+      {
+        "s": {"type": "uri", "value": "http://example.org/a"},
+        "p": {"type": "uri", "value": "http://example.org/p"}
+      },
+      {
+        "s": {"type": "uri", "value": "http://example.org/a"},
+        "p": {"type": "uri", "value": "http://example.org/q"},
+        "o": {"type": "bnode", "value": "b0"}
+      },
+      {
+        "s": {"type": "uri", "value": "http://example.org/a"},
+        "p": {"type": "uri", "value": "http://example.org/q"},
+        "o": {"type": "literal", "value": "plain \"quoted\""}
+      },
+      {
+        "s": {"type": "uri", "value": "http://example.org/a"},
+        "p": {"type": "uri", "value": "http://example.org/p"},
+        "o": {"type": "literal", "value": "colour", "xml:lang": "en-GB-oxendict"}
+      }
+    ]
+  }
+}`
+	want := `<http://example.org/a> <http://example.org/p> "hei"@no .
+_:b0 <http://example.org/p> "1"^^<http://www.w3.org/2001/XMLSchema#integer> .
+<http://example.org/a> <http://example.org/q> _:b0 .
+<http://example.org/a> <http://example.org/q> "plain \"quoted\"" .
+<http://example.org/a> <http://example.org/p> "colour"@en-GB-oxendict .
+`
+	ts, err := DecodeSPARQLJSONAsTriples(strings.NewReader(input), "s", "p", "o")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	for _, tr := range ts {
+		b.WriteString(tr.Serialize(NTriples))
+	}
+	if b.String() != want {
+		t.Errorf("DecodeSPARQLJSONAsTriples =>\n%s\nwant:\n%s", b.String(), want)
+	}
+
+	errTests := []struct {
+		input string
+		want  string
+	}{
+		{`{"results": {"bindings": [{"s": {"type": "literal", "value": "x"}, "p": {"type": "uri", "value": "http://example.org/p"}, "o": {"type": "uri", "value": "http://example.org/o"}}]}}`,
+			"result 0: ?s: literal not valid as subject"},
+		{`{"results": {"bindings": [{"s": {"type": "uri", "value": "http://example.org/s"}, "p": {"type": "bnode", "value": "p"}, "o": {"type": "uri", "value": "http://example.org/o"}}]}}`,
+			"result 0: ?p: bnode not valid as predicate"},
+		{`{"results": {"bindings": [{"s": {"type": "uri", "value": "http://example.org/s"}, "p": {"type": "uri", "value": "http://example.org/p"}, "o": {"type": "triple", "value": "x"}}]}}`,
+			`result 0: ?o: unknown term type: "triple"`},
+		{`{"results": {"bindings": [{"s": {"type": "uri", "value": "not an iri"}, "p": {"type": "uri", "value": "http://example.org/p"}, "o": {"type": "uri", "value": "http://example.org/o"}}]}}`,
+			"result 0: ?s: disallowed character: ' '"},
+		{`{"results": {"bindings": [{"s": {"type": "uri", "value": "http://example.org/s"}, "p": {"type": "uri", "value": "http://example.org/p"}, "o": {"type": "literal", "value": "x", "xml:lang": "en-toolongsubtag"}}]}}`,
+			`result 0: ?o: invalid language tag "en-toolongsubtag": subtags must have 1 to 8 characters`},
+	}
+	for _, tt := range errTests {
+		_, err := DecodeSPARQLJSONAsTriples(strings.NewReader(tt.input), "s", "p", "o")
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("DecodeSPARQLJSONAsTriples(%s) => %v; want %v", tt.input, err, tt.want)
+		}
+	}
+}
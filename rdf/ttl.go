@@ -1,10 +1,12 @@
 package rdf
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"runtime"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -18,11 +20,17 @@ type ttlDecoder struct {
 	tokens    [3]token          // 3 token lookahead
 	peekCount int               // number of tokens peeked at (position in tokens lookahead array)
 	current   ctxTriple         // the current triple beeing parsed
+	max       int               // maximum number of triples to decode (0 for no limit)
+	n         int               // number of triples decoded
 
 	// ctxStack keeps track of current and parent triple contexts,
 	// needed for parsing recursive structures (list/collections).
 	ctxStack []ctxTriple
 
+	// nesting holds the opening tokens of the structures ([, (, {| and <<)
+	// enclosing the current position, to give the context of errors.
+	nesting []token
+
 	// triples contains complete triples ready to be emitted. Usually it will have just one triple,
 	// but can have more when parsing nested list/collections. Decode() will always return the first item.
 	triples []Triple
@@ -46,6 +54,12 @@ func (d *ttlDecoder) SetOption(o ParseOption, v interface{}) error {
 			return fmt.Errorf("ParseOption \"Base\" must be an IRI.")
 		}
 		d.base = iri
+	case MaxTriples:
+		n, err := maxTriplesOption(v)
+		if err != nil {
+			return err
+		}
+		d.max = n
 	default:
 		return fmt.Errorf("RDF/XML decoder doesn't support option: %v", o)
 	}
@@ -56,6 +70,14 @@ func (d *ttlDecoder) SetOption(o ParseOption, v interface{}) error {
 func (d *ttlDecoder) Decode() (t Triple, err error) {
 	defer d.recover(&err)
 
+	if d.max > 0 && d.n >= d.max {
+		// Limit reached; no need to lex the rest of the input.
+		d.l.stop()
+	}
+	if d.l.stopped {
+		return t, io.EOF
+	}
+
 	// Check if there is allready a triple in the pipeline:
 	if len(d.triples) >= 1 {
 		goto done
@@ -80,6 +102,12 @@ func (d *ttlDecoder) Decode() (t Triple, err error) {
 done:
 	t = d.triples[0]
 	d.triples = d.triples[1:]
+
+	d.n++
+	if d.max > 0 && d.n >= d.max {
+		// Limit reached; no need to lex the rest of the input.
+		d.l.stop()
+	}
 	return t, err
 }
 
@@ -107,27 +135,37 @@ func parseStart(d *ttlDecoder) parseFn {
 		tok := d.expectAs("prefix IRI", tokenIRIAbs, tokenIRIRel)
 		if tok.typ == tokenIRIRel {
 			// Resolve against document base IRI
-			d.ns[label.text] = d.base.str + tok.text
+			d.ns[label.text] = ResolveIRI(d.base, tok.text).str
 		} else {
 			d.ns[label.text] = tok.text
 		}
 		d.expect1As("directive trailing dot", tokenDot)
 	case tokenSparqlPrefix:
 		label := d.expect1As("prefix label", tokenPrefixLabel)
-		uri := d.expect1As("prefix IRI", tokenIRIAbs)
-		d.ns[label.text] = uri.text
+		tok := d.expectAs("prefix IRI", tokenIRIAbs, tokenIRIRel)
+		if tok.typ == tokenIRIRel {
+			// Resolve against document base IRI
+			d.ns[label.text] = ResolveIRI(d.base, tok.text).str
+		} else {
+			d.ns[label.text] = tok.text
+		}
 	case tokenBase:
 		tok := d.expectAs("base IRI", tokenIRIAbs, tokenIRIRel)
 		if tok.typ == tokenIRIRel {
 			// Resolve against document base IRI
-			d.base.str = d.base.str + tok.text
+			d.base = ResolveIRI(d.base, tok.text)
 		} else {
 			d.base.str = tok.text
 		}
 		d.expect1As("directive trailing dot", tokenDot)
 	case tokenSparqlBase:
-		uri := d.expect1As("base IRI", tokenIRIAbs)
-		d.base.str = uri.text
+		tok := d.expectAs("base IRI", tokenIRIAbs, tokenIRIRel)
+		if tok.typ == tokenIRIRel {
+			// Resolve against document base IRI
+			d.base = ResolveIRI(d.base, tok.text)
+		} else {
+			d.base.str = tok.text
+		}
 	case tokenEOF:
 		return nil
 	default:
@@ -146,13 +184,13 @@ func parseEnd(d *ttlDecoder) parseFn {
 		case tokenSemicolon:
 			// parse multiple semicolons in a row
 			return parseEnd
-		case tokenDot:
+		case tokenDot, tokenAnnotationEnd:
 			// parse trailing semicolon
 			return parseEnd
 		case tokenEOF:
 			// trailing semicolon without final dot not allowed
 			// TODO only allowed in property lists?
-			d.errorf("%d:%d: expected triple termination, got %v", tok.line, tok.col, tok.typ)
+			d.errorf(tok, "expected triple termination, got %v", tok.typ)
 			return nil
 		}
 		d.current.Pred = nil
@@ -164,6 +202,7 @@ func parseEnd(d *ttlDecoder) parseFn {
 		d.pushContext()
 		return nil
 	case tokenPropertyListEnd:
+		d.close()
 		d.popContext()
 		if d.peek().typ == tokenDot {
 			// Reached end of statement
@@ -177,10 +216,21 @@ func parseEnd(d *ttlDecoder) parseFn {
 		}
 		// Property list was object, need to check for more closing property lists.
 		return parseEnd
+	case tokenAnnotationEnd:
+		// Restore the annotated triple
+		d.close()
+		d.popContext()
+		if d.peek().typ == tokenDot {
+			// Reached end of statement
+			d.next()
+			return nil
+		}
+		return parseEnd
 	case tokenCollectionEnd:
+		d.close()
 		// Emit collection closing triple { bnode rdf:rest rdf:nil }
 		d.current.Pred = IRI{str: "http://www.w3.org/1999/02/22-rdf-syntax-ns#rest"}
-		d.current.Obj = IRI{str: "http://www.w3.org/1999/02/22-rdf-syntax-ns#nil"}
+		d.current.Obj = RDFNil
 		d.emit()
 
 		// Restore parent triple
@@ -197,8 +247,10 @@ func parseEnd(d *ttlDecoder) parseFn {
 			return parseEnd
 		}
 		return nil
-	case tokenError:
-		d.errorf("%d:%d: syntax error: %v", tok.line, tok.col, tok.text)
+	case tokenEOF:
+		panic(&ParseError{Line: tok.line, Col: tok.col, Err: fmt.Errorf("%w: expected triple termination", ErrUnexpectedEOF)})
+	case tokenError, tokenErrorEOF, tokenErrorRead:
+		d.unexpected(tok, "triple termination")
 		return nil
 	default:
 		if d.current.Ctx == ctxColl {
@@ -215,7 +267,7 @@ func parseEnd(d *ttlDecoder) parseFn {
 			d.pushContext()
 			return nil
 		}
-		d.errorf("%d:%d: expected triple termination, got %v", tok.line, tok.col, tok.typ)
+		d.errorf(tok, "expected triple termination, got %v", tok.typ)
 		return nil
 	}
 
@@ -237,7 +289,7 @@ func parseSubject(d *ttlDecoder) parseFn {
 	case tokenIRIAbs:
 		d.current.Subj = IRI{str: tok.text}
 	case tokenIRIRel:
-		d.current.Subj = IRI{str: d.base.str + tok.text}
+		d.current.Subj = ResolveIRI(d.base, tok.text)
 	case tokenBNode:
 		d.current.Subj = Blank{id: tok.text}
 	case tokenAnonBNode:
@@ -246,12 +298,13 @@ func parseSubject(d *ttlDecoder) parseFn {
 	case tokenPrefixLabel:
 		ns, ok := d.ns[tok.text]
 		if !ok {
-			d.errorf("missing namespace for prefix: '%s'", tok.text)
+			d.errorf(tok, "missing namespace for prefix: '%s'", tok.text)
 		}
 		suf := d.expect1As("IRI suffix", tokenIRISuffix)
 		d.current.Subj = IRI{str: ns + suf.text}
 	case tokenPropertyListStart:
 		// Blank node is subject of a new triple
+		d.open(tok)
 		d.bnodeN++
 		d.current.Subj = Blank{id: fmt.Sprintf("_:b%d", d.bnodeN)}
 		d.pushContext() // Subj = bnode, top context
@@ -259,19 +312,21 @@ func parseSubject(d *ttlDecoder) parseFn {
 	case tokenCollectionStart:
 		if d.peek().typ == tokenCollectionEnd {
 			// An empty collection
-			d.current.Subj = IRI{str: "http://www.w3.org/1999/02/22-rdf-syntax-ns#nil"}
+			d.next() // consume ')'
+			d.current.Subj = RDFNil
 			break
 		}
+		d.open(tok)
 		d.bnodeN++
 		d.current.Subj = Blank{id: fmt.Sprintf("_:b%d", d.bnodeN)}
 		d.pushContext()
 		d.current.Pred = IRI{str: "http://www.w3.org/1999/02/22-rdf-syntax-ns#first"}
 		d.current.Ctx = ctxColl
 		return parseObject
-	case tokenError:
-		d.errorf("%d:%d: syntax error: %v", tok.line, tok.col, tok.text)
+	case tokenQuotedTripleStart:
+		d.current.Subj = d.parseQuotedTriple(tok)
 	default:
-		d.errorf("unexpected %v as subject", tok.typ)
+		d.unexpected(tok, "subject")
 	}
 
 	return parsePredicate
@@ -286,20 +341,18 @@ func parsePredicate(d *ttlDecoder) parseFn {
 	case tokenIRIAbs:
 		d.current.Pred = IRI{str: tok.text}
 	case tokenIRIRel:
-		d.current.Pred = IRI{str: d.base.str + tok.text}
+		d.current.Pred = ResolveIRI(d.base, tok.text)
 	case tokenRDFType:
-		d.current.Pred = IRI{str: "http://www.w3.org/1999/02/22-rdf-syntax-ns#type"}
+		d.current.Pred = RDFType
 	case tokenPrefixLabel:
 		ns, ok := d.ns[tok.text]
 		if !ok {
-			d.errorf("missing namespace for prefix: '%s'", tok.text)
+			d.errorf(tok, "missing namespace for prefix: '%s'", tok.text)
 		}
 		suf := d.expect1As("IRI suffix", tokenIRISuffix)
 		d.current.Pred = IRI{str: ns + suf.text}
-	case tokenError:
-		d.errorf("%d:%d: syntax error: %v", tok.line, tok.col, tok.text)
 	default:
-		d.errorf("%d:%d: unexpected %v as predicate", tok.line, tok.col, tok.typ)
+		d.unexpected(tok, "predicate")
 	}
 
 	return parseObject
@@ -311,71 +364,25 @@ func parseObject(d *ttlDecoder) parseFn {
 	case tokenIRIAbs:
 		d.current.Obj = IRI{str: tok.text}
 	case tokenIRIRel:
-		d.current.Obj = IRI{str: d.base.str + tok.text}
+		d.current.Obj = ResolveIRI(d.base, tok.text)
 	case tokenBNode:
 		d.current.Obj = Blank{id: tok.text}
 	case tokenAnonBNode:
 		d.bnodeN++
 		d.current.Obj = Blank{id: fmt.Sprintf("_:b%d", d.bnodeN)}
-	case tokenLiteral, tokenLiteral3:
-		val := tok.text
-		l := Literal{
-			str:      val,
-			DataType: xsdString,
-		}
-		p := d.peek()
-		switch p.typ {
-		case tokenLangMarker:
-			d.next() // consume peeked token
-			tok = d.expect1As("literal language", tokenLang)
-			l.lang = tok.text
-			l.DataType = rdfLangString
-		case tokenDataTypeMarker:
-			d.next() // consume peeked token
-			tok = d.expectAs("literal datatype", tokenIRIAbs, tokenPrefixLabel)
-			switch tok.typ {
-			case tokenIRIAbs:
-				l.DataType = IRI{str: tok.text}
-			case tokenPrefixLabel:
-				ns, ok := d.ns[tok.text]
-				if !ok {
-					d.errorf("missing namespace for prefix: '%s'", tok.text)
-				}
-				tok2 := d.expect1As("IRI suffix", tokenIRISuffix)
-				l.DataType = IRI{str: ns + tok2.text}
-			}
-		}
-		d.current.Obj = l
-	case tokenLiteralDouble:
-		d.current.Obj = Literal{
-			str:      tok.text,
-			DataType: xsdDouble,
-		}
-	case tokenLiteralDecimal:
-		d.current.Obj = Literal{
-			str:      tok.text,
-			DataType: xsdDecimal,
-		}
-	case tokenLiteralInteger:
-		d.current.Obj = Literal{
-			str:      tok.text,
-			DataType: xsdInteger,
-		}
-	case tokenLiteralBoolean:
-		d.current.Obj = Literal{
-			str:      tok.text,
-			DataType: xsdBoolean,
-		}
+	case tokenLiteral, tokenLiteral3, tokenLiteralDouble, tokenLiteralDecimal, tokenLiteralInteger, tokenLiteralBoolean:
+		d.current.Obj = d.literal(tok)
 	case tokenPrefixLabel:
 		ns, ok := d.ns[tok.text]
 		if !ok {
-			d.errorf("missing namespace for prefix: '%s'", tok.text)
+			d.errorf(tok, "missing namespace for prefix: '%s'", tok.text)
 		}
 		suf := d.expect1As("IRI suffix", tokenIRISuffix)
 		d.current.Obj = IRI{str: ns + suf.text}
 	case tokenPropertyListStart:
 		// Blank node is object of current triple
 		// Save current context, to be restored after the list ends
+		d.open(tok)
 		d.pushContext()
 
 		d.bnodeN++
@@ -393,11 +400,12 @@ func parseObject(d *ttlDecoder) parseFn {
 		if d.peek().typ == tokenCollectionEnd {
 			// an empty collection
 			d.next() // consume ')'
-			d.current.Obj = IRI{str: "http://www.w3.org/1999/02/22-rdf-syntax-ns#nil"}
+			d.current.Obj = RDFNil
 			break
 		}
 		// Blank node is object of current triple
 		// Save current context, to be restored after the collection ends
+		d.open(tok)
 		d.pushContext()
 
 		d.bnodeN++
@@ -409,18 +417,144 @@ func parseObject(d *ttlDecoder) parseFn {
 		d.current.Ctx = ctxColl
 		d.pushContext()
 		return nil
-	case tokenError:
-		d.errorf("%d:%d: syntax error: %v", tok.line, tok.col, tok.text)
+	case tokenQuotedTripleStart:
+		d.current.Obj = d.parseQuotedTriple(tok)
 	default:
-		d.errorf("%d:%d: unexpected %v as object", tok.line, tok.col, tok.typ)
+		d.unexpected(tok, "object")
 	}
 
 	// We now have a full tripe, emit it.
 	d.emit()
 
+	if d.peek().typ == tokenAnnotationStart {
+		d.open(d.next()) // consume '{|'
+
+		// Save current context, to be restored after the annotation ends
+		d.pushContext()
+
+		// Set the quoted triple as subject of the annotation triples.
+		d.current.Subj = QuotedTriple{Triple: d.current.Triple}
+		d.current.Pred = nil
+		d.current.Obj = nil
+		d.current.Ctx = ctxAnnotation
+		d.pushContext()
+		return nil
+	}
+
 	return parseEnd
 }
 
+// literal parses a literal, starting with the given literal token,
+// including any language tag or datatype following it.
+func (d *ttlDecoder) literal(tok token) Literal {
+	switch tok.typ {
+	case tokenLiteralDouble:
+		return Literal{str: tok.text, DataType: xsdDouble}
+	case tokenLiteralDecimal:
+		return Literal{str: tok.text, DataType: xsdDecimal}
+	case tokenLiteralInteger:
+		return Literal{str: tok.text, DataType: xsdInteger}
+	case tokenLiteralBoolean:
+		return Literal{str: tok.text, DataType: xsdBoolean}
+	}
+	l := Literal{
+		str:      tok.text,
+		DataType: xsdString,
+	}
+	switch d.peek().typ {
+	case tokenLangMarker:
+		d.next() // consume peeked token
+		tok = d.expect1As("literal language", tokenLang)
+		l.lang = tok.text
+		l.DataType = rdfLangString
+	case tokenDataTypeMarker:
+		d.next() // consume peeked token
+		tok = d.expectAs("literal datatype", tokenIRIAbs, tokenPrefixLabel)
+		switch tok.typ {
+		case tokenIRIAbs:
+			l.DataType = IRI{str: tok.text}
+		case tokenPrefixLabel:
+			ns, ok := d.ns[tok.text]
+			if !ok {
+				d.errorf(tok, "missing namespace for prefix: '%s'", tok.text)
+			}
+			tok2 := d.expect1As("IRI suffix", tokenIRISuffix)
+			l.DataType = IRI{str: ns + tok2.text}
+		}
+	}
+	return l
+}
+
+// parseQuotedTriple parses a RDF-star quoted triple, after the opening '<<'
+// token start.
+func (d *ttlDecoder) parseQuotedTriple(start token) QuotedTriple {
+	var q QuotedTriple
+	d.open(start)
+
+	tok := d.next()
+	switch tok.typ {
+	case tokenIRIAbs, tokenIRIRel, tokenPrefixLabel:
+		q.Subj = d.iri(tok)
+	case tokenBNode:
+		q.Subj = Blank{id: tok.text}
+	case tokenAnonBNode:
+		d.bnodeN++
+		q.Subj = Blank{id: fmt.Sprintf("_:b%d", d.bnodeN)}
+	case tokenQuotedTripleStart:
+		q.Subj = d.parseQuotedTriple(tok)
+	default:
+		d.unexpected(tok, "quoted triple subject")
+	}
+
+	tok = d.next()
+	switch tok.typ {
+	case tokenIRIAbs, tokenIRIRel, tokenPrefixLabel:
+		q.Pred = d.iri(tok)
+	case tokenRDFType:
+		q.Pred = RDFType
+	default:
+		d.unexpected(tok, "quoted triple predicate")
+	}
+
+	tok = d.next()
+	switch tok.typ {
+	case tokenIRIAbs, tokenIRIRel, tokenPrefixLabel:
+		q.Obj = d.iri(tok)
+	case tokenBNode:
+		q.Obj = Blank{id: tok.text}
+	case tokenAnonBNode:
+		d.bnodeN++
+		q.Obj = Blank{id: fmt.Sprintf("_:b%d", d.bnodeN)}
+	case tokenLiteral, tokenLiteral3, tokenLiteralDouble, tokenLiteralDecimal, tokenLiteralInteger, tokenLiteralBoolean:
+		q.Obj = d.literal(tok)
+	case tokenQuotedTripleStart:
+		q.Obj = d.parseQuotedTriple(tok)
+	default:
+		d.unexpected(tok, "quoted triple object")
+	}
+
+	d.expect1As("quoted triple end", tokenQuotedTripleEnd)
+	d.close()
+	return q
+}
+
+// iri parses an IRI, either absolute, relative or prefixed,
+// starting with the given token.
+func (d *ttlDecoder) iri(tok token) IRI {
+	switch tok.typ {
+	case tokenIRIRel:
+		return ResolveIRI(d.base, tok.text)
+	case tokenPrefixLabel:
+		ns, ok := d.ns[tok.text]
+		if !ok {
+			d.errorf(tok, "missing namespace for prefix: '%s'", tok.text)
+		}
+		suf := d.expect1As("IRI suffix", tokenIRISuffix)
+		return IRI{str: ns + suf.text}
+	}
+	return IRI{str: tok.text}
+}
+
 // pushContext pushes the current triple and context to the context stack.
 func (d *ttlDecoder) pushContext() {
 	d.ctxStack = append(d.ctxStack, d.current)
@@ -444,6 +578,18 @@ func (d *ttlDecoder) popContext() {
 	}
 }
 
+// open records the opening token of a nested structure.
+func (d *ttlDecoder) open(tok token) {
+	d.nesting = append(d.nesting, tok)
+}
+
+// close forgets the innermost nested structure, when it is closed.
+func (d *ttlDecoder) close() {
+	if len(d.nesting) > 0 {
+		d.nesting = d.nesting[:len(d.nesting)-1]
+	}
+}
+
 // emit adds the current triple to the slice of completed triples.
 func (d *ttlDecoder) emit() {
 	d.triples = append(d.triples, d.current.Triple)
@@ -493,15 +639,15 @@ func (d *ttlDecoder) backup3(t2, t1 token) {
 // parseFn represents the state of the parser as a function that returns the next state.
 type parseFn func(*ttlDecoder) parseFn
 
-// errorf formats the error and terminates parsing.
-func (d *ttlDecoder) errorf(format string, args ...interface{}) {
-	format = fmt.Sprintf("%s", format)
-	panic(fmt.Errorf(format, args...))
+// errorf formats the error, at the position of the given token, and
+// terminates parsing.
+func (d *ttlDecoder) errorf(t token, format string, args ...interface{}) {
+	panic(&ParseError{Line: t.line, Col: t.col, Err: fmt.Errorf(format, args...)})
 }
 
 // unexpected complains about the given token and terminates parsing.
 func (d *ttlDecoder) unexpected(t token, context string) {
-	d.errorf("%d:%d unexpected %v as %s", t.line, t.col, t.typ, context)
+	panic(unexpectedErr(t, context))
 }
 
 // recover catches non-runtime panics and binds the panic error
@@ -514,20 +660,51 @@ func (d *ttlDecoder) recover(errp *error) {
 			panic(e)
 		}
 		//d.stop() something to clean up?
-		*errp = e.(error)
+		*errp = formatErr(e.(error), Turtle)
+		var perr *ParseError
+		if len(d.nesting) > 0 && errors.As(*errp, &perr) {
+			perr.Err = fmt.Errorf("%s: %w", d.nestingContext(), perr.Err)
+		}
 	}
 	return
 }
 
+// nestingContext describes the nested structures enclosing the current
+// position, outermost first, e.g. "in collection started at 10:5: in blank
+// node property list started at 12:2".
+func (d *ttlDecoder) nestingContext() string {
+	var b strings.Builder
+	for i, tok := range d.nesting {
+		if i > 0 {
+			b.WriteString(": ")
+		}
+		fmt.Fprintf(&b, "in %s started at %d:%d", nestingName(tok.typ), tok.line, tok.col)
+	}
+	return b.String()
+}
+
+// nestingName returns the name of the nested structure opened by a token
+// of the given type.
+func nestingName(typ tokenType) string {
+	switch typ {
+	case tokenPropertyListStart:
+		return "blank node property list"
+	case tokenCollectionStart:
+		return "collection"
+	case tokenAnnotationStart:
+		return "annotation"
+	case tokenQuotedTripleStart:
+		return "quoted triple"
+	default:
+		return "nested structure"
+	}
+}
+
 // expect1As consumes the next token and guarantees that it has the expected type.
 func (d *ttlDecoder) expect1As(context string, expected tokenType) token {
 	t := d.next()
 	if t.typ != expected {
-		if t.typ == tokenError {
-			d.errorf("%d:%d: syntax error: %s", t.line, t.col, t.text)
-		} else {
-			d.unexpected(t, context)
-		}
+		d.unexpected(t, context)
 	}
 	return t
 }
@@ -540,15 +717,12 @@ func (d *ttlDecoder) expectAs(context string, expected ...tokenType) token {
 			return t
 		}
 	}
-	if t.typ == tokenError {
-		d.errorf("syntax error: %s", t.text)
-	} else {
-		d.unexpected(t, context)
-	}
+	d.unexpected(t, context)
 	return t
 }
 
-// parseLiteral
+// parseLiteral returns the Go value of the lexical form val of the given
+// datatype, as for Literal.Typed; see the xsd datatype variables.
 func parseLiteral(val, datatype string) (interface{}, error) {
 	switch datatype {
 	case xsdString.str:
@@ -583,6 +757,13 @@ func parseLiteral(val, datatype string) (interface{}, error) {
 			return t, nil
 		}
 		return t, nil
+	case xsdDate.str:
+		t, err := time.Parse("2006-01-02Z07:00", val)
+		if err != nil {
+			// The timezone is optional
+			return time.Parse("2006-01-02", val)
+		}
+		return t, nil
 	case xsdByte.str:
 		return []byte(val), nil
 		// TODO: other xsd dataypes that maps to Go data types
@@ -603,6 +784,7 @@ const (
 	ctxTop context = iota
 	ctxColl
 	ctxList
+	ctxAnnotation
 )
 
 // TODO remove when done
@@ -614,6 +796,8 @@ func (ctx context) String() string {
 		return "list"
 	case ctxColl:
 		return "collection"
+	case ctxAnnotation:
+		return "annotation"
 
 	default:
 		return "unknown context"
@@ -2,6 +2,7 @@ package rdf
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"reflect"
 	"strings"
@@ -251,92 +252,109 @@ ericFoaf:ericP :givenName "Eric" ;
 var ttlBenchOutputs = []string{
 	`@prefix ns0:	<http://example.org/#> .
 @prefix ns1:	<http://www.perceive.net/schemas/relationship/> .
-ns0:green-goblin	ns1:enemyOf	ns0:spiderman .
 @prefix ns2:	<http://xmlns.com/foaf/0.1/> .
-ns0:green-goblin	a	ns2:Person ;
+ns0:green-goblin	ns1:enemyOf	ns0:spiderman ;
+	a	ns2:Person ;
 	ns2:name	"Green Goblin" .
 ns0:spiderman	ns1:enemyOf	ns0:green-goblin ;
 	a	ns2:Person ;
 	ns2:name	"Spiderman" ,
-			"Человек-паук"@ru .`,
+			"Человек-паук"@ru .
+`,
 
 	`@prefix ns0:	<http://example.org/#> .
 @prefix ns1:	<http://www.perceive.net/schemas/relationship/> .
-ns0:spiderman	ns1:enemyOf	ns0:green-goblin .`,
+ns0:spiderman	ns1:enemyOf	ns0:green-goblin .
+`,
 
 	`@prefix ns0:	<http://example.org/#> .
 @prefix ns1:	<http://www.perceive.net/schemas/relationship/> .
-ns0:spiderman	ns1:enemyOf	ns0:green-goblin .
 @prefix ns2:	<http://xmlns.com/foaf/0.1/> .
-ns0:spiderman	ns2:name	"Spiderman" .`,
+ns0:spiderman	ns1:enemyOf	ns0:green-goblin ;
+	ns2:name	"Spiderman" .
+`,
 
 	`@prefix ns0:	<http://example.org/#> .
 @prefix ns1:	<http://www.perceive.net/schemas/relationship/> .
-ns0:spiderman	ns1:enemyOf	ns0:green-goblin .
 @prefix ns2:	<http://xmlns.com/foaf/0.1/> .
-ns0:spiderman	ns2:name	"Spiderman" .`,
+ns0:spiderman	ns1:enemyOf	ns0:green-goblin ;
+	ns2:name	"Spiderman" .
+`,
 
-	`@prefix ns0:	<http://xmlns.com/foaf/0.1/> .
-@prefix ns1:	<http://example.org/#> .
-ns1:spiderman	ns0:name	"Spiderman" ,
-			"Человек-паук"@ru .`,
+	`@prefix ns0:	<http://example.org/#> .
+@prefix ns1:	<http://xmlns.com/foaf/0.1/> .
+ns0:spiderman	ns1:name	"Spiderman" ,
+			"Человек-паук"@ru .
+`,
 
-	`@prefix ns0:	<http://xmlns.com/foaf/0.1/> .
-@prefix ns1:	<http://example.org/#> .
-ns1:spiderman	ns0:name	"Spiderman" ,
-			"Человек-паук"@ru .`,
+	`@prefix ns0:	<http://example.org/#> .
+@prefix ns1:	<http://xmlns.com/foaf/0.1/> .
+ns0:spiderman	ns1:name	"Spiderman" ,
+			"Человек-паук"@ru .
+`,
 
 	`@prefix ns0:	<http://example.org/#> .
 @prefix ns1:	<http://www.perceive.net/schemas/relationship/> .
-ns0:green-goblin	ns1:enemyOf	ns0:spiderman .`,
+ns0:green-goblin	ns1:enemyOf	ns0:spiderman .
+`,
 
 	`@prefix ns0:	<http://example.org/#> .
 @prefix ns1:	<http://www.perceive.net/schemas/relationship/> .
-ns0:green-goblin	ns1:enemyOf	ns0:spiderman .`,
+ns0:green-goblin	ns1:enemyOf	ns0:spiderman .
+`,
 
 	`@prefix ns0:	<http://another.example/> .
+@prefix ns1:	<http://one.example/> .
+@prefix ns2:	<http://one.example/path/> .
+@prefix ns3:	<http://two.example/> .
+@prefix ns4:	<http://伝言.example/> .
 ns0:subject5	ns0:predicate5	ns0:object5 .
 ns0:subject6	a	ns0:subject7 .
-@prefix ns1:	<http://one.example/path/> .
-ns1:subject4	ns1:predicate4	ns1:object4 .
-@prefix ns2:	<http://one.example/> .
-ns2:subject1	ns2:predicate1	ns2:object1 .
-ns2:subject2	ns2:predicate2	ns2:object2 .
-@prefix ns3:	<http://two.example/> .
+ns2:subject4	ns2:predicate4	ns2:object4 .
+ns1:subject1	ns1:predicate1	ns1:object1 .
+ns1:subject2	ns1:predicate2	ns1:object2 .
 ns3:subject3	ns3:predicate3	ns3:object3 .
-@prefix ns4:	<http://伝言.example/> .
-ns4:?user=أكرم&amp;channel=R%26D	a	ns0:subject8 .`,
+ns4:\?user\=أكرم\&amp\;channel\=R%26D	a	ns0:subject8 .
+`,
 
-	`@prefix ns0:	<http://xmlns.com/foaf/0.1/> .
-@prefix ns1:	<http://example.org/#> .
-ns1:green-goblin	ns0:name	"Green Goblin" .
-ns1:spiderman	ns0:name	"Spiderman" .`,
+	`@prefix ns0:	<http://example.org/#> .
+@prefix ns1:	<http://xmlns.com/foaf/0.1/> .
+ns0:green-goblin	ns1:name	"Green Goblin" .
+ns0:spiderman	ns1:name	"Spiderman" .
+`,
 
 	`@prefix ns0:	<http://example.org/vocab/show/> .
-ns0:218	ns0:blurb	"This is a multi-line\nliteral with many quotes (\"\"\"\"\")\nand up to two sequential apostrophes ('')." ;
+@prefix ns1:	<http://www.w3.org/2000/01/rdf-schema#> .
+ns0:218	ns0:blurb	'''This is a multi-line
+literal with many quotes (""""")
+and up to two sequential apostrophes ('').''' ;
 	ns0:localName	"That Seventies Show"@en ,
 			"Cette Série des Années Soixante-dix"@fr ,
-			"Cette Série des Années Septante"@fr-be .
-@prefix ns1:	<http://www.w3.org/2000/01/rdf-schema#> .
-ns0:218	ns1:label	"That Seventies Show" .`,
+			"Cette Série des Années Septante"@fr-be ;
+	ns1:label	"That Seventies Show" .
+`,
 
-	`@prefix ns0:	<http://example.org/> .
-@prefix ns1:	<http://en.wikipedia.org/wiki/> .
-ns1:Helium	ns0:elementsatomicMass	4.002602 ;
-	ns0:elementsatomicNumber	2 ;
-	ns0:elementsspecificGravity	1.663E-4 .`,
+	`@prefix ns0:	<http://en.wikipedia.org/wiki/> .
+@prefix ns1:	<http://example.org/> .
+ns0:Helium	ns1:elementsatomicMass	4.002602 ;
+	ns1:elementsatomicNumber	2 ;
+	ns1:elementsspecificGravity	1.663E-4 .
+`,
 
 	`@prefix ns0:	<http://example.org/> .
 @prefix ns1:	<http://somecountry.example/> .
-ns1:census2007	ns0:statsisLandlocked	false .`,
+ns1:census2007	ns0:statsisLandlocked	false .
+`,
 
 	`@prefix ns0:	<http://xmlns.com/foaf/0.1/> .
 _:alice	ns0:knows	_:bob .
-_:bob	ns0:knows	_:alice .`,
+_:bob	ns0:knows	_:alice .
+`,
 
 	`@prefix ns0:	<http://xmlns.com/foaf/0.1/> .
 _:b1	ns0:knows	_:b2 .
-_:b2	ns0:name	"Bob" .`,
+_:b2	ns0:name	"Bob" .
+`,
 
 	`@prefix ns0:	<http://xmlns.com/foaf/0.1/> .
 _:b1	ns0:knows	_:b2 ;
@@ -344,7 +362,8 @@ _:b1	ns0:knows	_:b2 ;
 _:b2	ns0:knows	_:b3 ;
 	ns0:mbox	<bob@example.com> ;
 	ns0:name	"Bob" .
-_:b3	ns0:name	"Eve" .`,
+_:b3	ns0:name	"Eve" .
+`,
 
 	`@prefix ns0:	<http://xmlns.com/foaf/0.1/> .
 _:a	ns0:knows	_:b ;
@@ -352,10 +371,11 @@ _:a	ns0:knows	_:b ;
 _:b	ns0:knows	_:c ;
 	ns0:mbox	<bob@example.com> ;
 	ns0:name	"Bob" .
-_:c	ns0:name	"Eve" .`,
+_:c	ns0:name	"Eve" .
+`,
 
-	`@prefix rdf:	<http://www.w3.org/1999/02/22-rdf-syntax-ns#> .
-@prefix ns0:	<http://example.org/> .
+	`@prefix ns0:	<http://example.org/> .
+@prefix rdf:	<http://www.w3.org/1999/02/22-rdf-syntax-ns#> .
 ns0:foosubject	ns0:foopredicate2	rdf:nil ;
 	ns0:foopredicate	_:b1 .
 _:b1	rdf:first	ns0:fooa ;
@@ -363,60 +383,69 @@ _:b1	rdf:first	ns0:fooa ;
 _:b2	rdf:first	ns0:foob ;
 	rdf:rest	_:b3 .
 _:b3	rdf:first	ns0:fooc ;
-	rdf:rest	rdf:nil .`,
+	rdf:rest	rdf:nil .
+`,
 
 	`@prefix ns0:	<http://example.org/stuff/1.0/> .
-@prefix ns1:	<http://www.w3.org/TR/> .
-ns1:rdf-syntax-grammar	ns0:editor	_:b1 .
-@prefix ns2:	<http://purl.org/dc/elements/1.1/> .
-ns1:rdf-syntax-grammar	ns2:title	"RDF/XML Syntax Specification (Revised)" .
-_:b1	ns0:fullname	"Dave Beckett" .
-@prefix ns3:	<http://purl.org/net/dajobe/> .
-_:b1	ns0:homePage	ns3: .`,
+@prefix ns1:	<http://purl.org/dc/elements/1.1/> .
+@prefix ns2:	<http://purl.org/net/dajobe/> .
+@prefix ns3:	<http://www.w3.org/TR/> .
+ns3:rdf-syntax-grammar	ns0:editor	_:b1 ;
+	ns1:title	"RDF/XML Syntax Specification (Revised)" .
+_:b1	ns0:fullname	"Dave Beckett" ;
+	ns0:homePage	ns2: .
+`,
 
 	`@prefix ns0:	<http://example.org/stuff/1.0/> .
-ns0:a	ns0:b	_:b1 .
 @prefix rdf:	<http://www.w3.org/1999/02/22-rdf-syntax-ns#> .
+ns0:a	ns0:b	_:b1 .
 _:b1	rdf:first	"apple" ;
 	rdf:rest	_:b2 .
 _:b2	rdf:first	"banana" ;
-	rdf:rest	rdf:nil .`,
+	rdf:rest	rdf:nil .
+`,
 
 	`@prefix ns0:	<http://example.org/stuff/1.0/> .
-ns0:a	ns0:b	_:b1 .
 @prefix rdf:	<http://www.w3.org/1999/02/22-rdf-syntax-ns#> .
+ns0:a	ns0:b	_:b1 .
 _:b1	rdf:first	"apple" ;
 	rdf:rest	_:b2 .
 _:b2	rdf:first	"banana" ;
-	rdf:rest	rdf:nil .`,
+	rdf:rest	rdf:nil .
+`,
 
 	`@prefix ns0:	<http://example.org/stuff/1.0/> .
-ns0:a	ns0:b	"The first line\nThe second line\n  more" .`,
+ns0:a	ns0:b	"""The first line
+The second line
+  more""" .
+`,
 
 	`@prefix ns0:	<http://example.org/stuff/1.0/> .
-_:b1	ns0:p	"w" .
 @prefix rdf:	<http://www.w3.org/1999/02/22-rdf-syntax-ns#> .
-_:b1	rdf:first	1 ;
+_:b1	ns0:p	"w" ;
+	rdf:first	1 ;
 	rdf:rest	_:b2 .
 _:b2	rdf:first	2.0 ;
 	rdf:rest	_:b3 .
 _:b3	rdf:first	3E1 ;
-	rdf:rest	rdf:nil .`,
+	rdf:rest	rdf:nil .
+`,
 
 	`@prefix ns0:	<http://example.org/stuff/1.0/> .
-_:b0	ns0:p	"w" .
 @prefix rdf:	<http://www.w3.org/1999/02/22-rdf-syntax-ns#> .
-_:b0	rdf:first	1 ;
+_:b0	ns0:p	"w" ;
+	rdf:first	1 ;
 	rdf:rest	_:b1 .
 _:b1	rdf:first	2.0 ;
 	rdf:rest	_:b2 .
 _:b2	rdf:first	3E1 ;
-	rdf:rest	rdf:nil .`,
+	rdf:rest	rdf:nil .
+`,
 
 	`@prefix ns0:	<http://example.org/stuff/1.0/> .
-_:b1	ns0:p2	ns0:q2 .
 @prefix rdf:	<http://www.w3.org/1999/02/22-rdf-syntax-ns#> .
-_:b1	rdf:first	1 ;
+_:b1	ns0:p2	ns0:q2 ;
+	rdf:first	1 ;
 	rdf:rest	_:b2 .
 _:b2	rdf:first	_:b3 ;
 	rdf:rest	_:b4 .
@@ -424,48 +453,53 @@ _:b3	ns0:p	ns0:q .
 _:b4	rdf:first	_:b5 ;
 	rdf:rest	rdf:nil .
 _:b5	rdf:first	2 ;
-	rdf:rest	rdf:nil .`,
+	rdf:rest	rdf:nil .
+`,
 
-	`@prefix rdf:	<http://www.w3.org/1999/02/22-rdf-syntax-ns#> .
+	`@prefix ns0:	<http://example.org/stuff/1.0/> .
+@prefix rdf:	<http://www.w3.org/1999/02/22-rdf-syntax-ns#> .
 _:b0	rdf:first	1 ;
 	rdf:rest	_:b1 .
 _:b1	rdf:first	_:b2 ;
 	rdf:rest	_:b3 .
-@prefix ns0:	<http://example.org/stuff/1.0/> .
 _:b2	ns0:p	ns0:q .
 _:b3	rdf:first	_:b4 ;
 	rdf:rest	rdf:nil .
 _:b4	rdf:first	2 ;
-	rdf:rest	rdf:nil .`,
-
-	`@prefix ns0:	<http://xmlns.com/foaf/0.1/> .
-@prefix ns1:	<http://www.w3.org/People/Eric/ericP-foaf.rdf#> .
-ns1:ericP	ns0:givenName	"Eric" .
-@prefix ns2:	<http://norman.walsh.name/knows/who/> .
-ns1:ericP	ns0:knows	ns2:dan-brickley ,
-			_:b1 .
-@prefix ns3:	<http://getopenid.com/> .
-ns1:ericP	ns0:knows	ns3:amyvdh .
-_:b1	ns0:mbox	<mailto:timbl@w3.org> .`,
+	rdf:rest	rdf:nil .
+`,
+
+	`@prefix ns0:	<http://getopenid.com/> .
+@prefix ns1:	<http://norman.walsh.name/knows/who/> .
+@prefix ns2:	<http://www.w3.org/People/Eric/ericP-foaf.rdf#> .
+@prefix ns3:	<http://xmlns.com/foaf/0.1/> .
+ns2:ericP	ns3:givenName	"Eric" ;
+	ns3:knows	ns1:dan-brickley ,
+			_:b1 ,
+			ns0:amyvdh .
+_:b1	ns3:mbox	<mailto:timbl@w3.org> .
+`,
 
 	`@prefix ns0:	<http://books.example.com/product-types/> .
-@prefix ns1:	<http://purl.org/dc/terms/> .
-@prefix ns2:	<http://books.example.com/products/> .
-ns2:9780596007683.BOOK	ns1:type	ns0:BOOK .
-@prefix ns3:	<http://purl.org/vocab/frbr/core#> .
-ns2:9780596007683.BOOK	a	ns3:Expression .
-ns2:9780596802189.EBOOK	ns1:type	ns0:EBOOK ;
-	a	ns3:Expression .
-@prefix ns4:	<http://books.example.com/works/> .
-ns4:45U8QJGZSQKDH8N	ns1:creator	"Wil Wheaton"@en ;
-	ns1:title	"Just a Geek"@en ;
-	ns3:realization	ns2:9780596007683.BOOK ,
-			ns2:9780596802189.EBOOK ;
-	a	ns3:Work .`,
-
-	`@prefix ns0:	<http://purl.org/vocab/frbr/core#> .
-@prefix ns1:	<http://books.example.com/works/> .
-ns1:45U8QJGZSQKDH8N	a	ns0:Work .`,
+@prefix ns1:	<http://books.example.com/products/> .
+@prefix ns2:	<http://books.example.com/works/> .
+@prefix ns3:	<http://purl.org/dc/terms/> .
+@prefix ns4:	<http://purl.org/vocab/frbr/core#> .
+ns1:9780596007683.BOOK	ns3:type	ns0:BOOK ;
+	a	ns4:Expression .
+ns1:9780596802189.EBOOK	ns3:type	ns0:EBOOK ;
+	a	ns4:Expression .
+ns2:45U8QJGZSQKDH8N	ns3:creator	"Wil Wheaton"@en ;
+	ns3:title	"Just a Geek"@en ;
+	ns4:realization	ns1:9780596007683.BOOK ,
+			ns1:9780596802189.EBOOK ;
+	a	ns4:Work .
+`,
+
+	`@prefix ns0:	<http://books.example.com/works/> .
+@prefix ns1:	<http://purl.org/vocab/frbr/core#> .
+ns0:45U8QJGZSQKDH8N	a	ns1:Work .
+`,
 }
 
 func BenchmarkDecodeTTL(b *testing.B) {
@@ -534,6 +568,287 @@ func TestTTL(t *testing.T) {
 	}
 }
 
+func TestTTLEmptyCollection(t *testing.T) {
+	input := `@prefix ex: <http://example.org/> .
+() ex:p () .
+ex:s ex:q ( ), ex:o ;
+	ex:r << ex:s ex:q ex:o >> .
+<< ex:s ex:q ex:o >> ex:r () .`
+
+	ts := mustParseTriples(t, input)
+	if len(ts) != 5 {
+		t.Fatalf("decoding %s => %v; want 5 triples", input, ts)
+	}
+	for _, tr := range ts[:2] {
+		if tr.Obj != Object(RDFNil) {
+			t.Errorf("decoding () => %v; want %v", tr.Obj, RDFNil)
+		}
+	}
+	if ts[0].Subj != Subject(RDFNil) || ts[4].Obj != Object(RDFNil) {
+		t.Errorf("decoding () => %v, %v; want %v", ts[0].Subj, ts[4].Obj, RDFNil)
+	}
+
+	for _, collections := range []bool{true, false} {
+		var buf bytes.Buffer
+		enc := NewTripleEncoder(&buf, Turtle)
+		enc.Collections = collections
+		if err := enc.EncodeAll(append([]Triple(nil), ts...)); err != nil {
+			t.Fatal(err)
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatal(err)
+		}
+		out := buf.String()
+		if collections != !strings.Contains(out, "nil") {
+			t.Errorf("Collections=%v: encoding rdf:nil =>\n%s", collections, out)
+		}
+		if collections && strings.Count(out, "()") != 4 {
+			t.Errorf("Collections=%v: encoding rdf:nil =>\n%s\nwant 4 ()", collections, out)
+		}
+
+		got := mustParseTriples(t, out)
+		g := NewGraph()
+		g.Add(got...)
+		if g.Len() != len(ts) {
+			t.Errorf("Collections=%v: round-trip => %v; want %v", collections, got, ts)
+		}
+		for _, tr := range ts {
+			if !g.Has(tr) {
+				t.Errorf("Collections=%v: round-trip lost %v", collections, tr)
+			}
+		}
+	}
+}
+
+func TestTTLLocalNames(t *testing.T) {
+	input := `@prefix ex: <http://example.org/> .
+ex:123 ex:_foo ex: .
+ex:0.5 ex::x ex:a-b .
+ex:c\.d\- ex:e%20f ex:g\~h .`
+
+	ts := mustParseTriples(t, input)
+	want := []string{
+		"http://example.org/123", "http://example.org/_foo", "http://example.org/",
+		"http://example.org/0.5", "http://example.org/:x", "http://example.org/a-b",
+		"http://example.org/c.d-", "http://example.org/e%20f", "http://example.org/g~h",
+	}
+	if len(ts) != 3 {
+		t.Fatalf("decoding %s => %v; want 3 triples", input, ts)
+	}
+	for i, tr := range ts {
+		for j, term := range []Term{tr.Subj, tr.Pred, tr.Obj} {
+			if term != Term(IRI{str: want[3*i+j]}) {
+				t.Errorf("decoding %s => %v; want <%s>", input, term, want[3*i+j])
+			}
+		}
+	}
+}
+
+func TestTTLNestedErrorContext(t *testing.T) {
+	tests := []struct {
+		input     string
+		line, col int
+		msg       string
+	}{
+		{
+			"@prefix ex: <http://ex/> .\nex:s ex:p (\n  ex:a\n  [ ex:q ex:b ;\n    ex:r . ]\n) .",
+			5, 10, "in collection started at 2:11: in blank node property list started at 4:4: unexpected Dot as object",
+		},
+		{
+			"@prefix ex: <http://ex/> .\nex:s ex:p [ ex:q ( ex:a ex:b",
+			2, 28, "in blank node property list started at 2:12: in collection started at 2:18: unexpected EOF: expected triple termination",
+		},
+		{
+			"@prefix ex: <http://ex/> .\nex:s ex:p ex:o {| ex:q << ex:a ex:b \"x\"@ >> |} .",
+			2, 41, "in annotation started at 2:17: in quoted triple started at 2:25: syntax error: bad literal: invalid language tag",
+		},
+		{
+			// No context once the structures are closed.
+			"@prefix ex: <http://ex/> .\n[ ex:q ex:o ] ex:p ( ex:a ) .\nex:t ex:p nope:o .",
+			3, 10, "missing namespace for prefix: 'nope'",
+		},
+	}
+	for _, test := range tests {
+		_, err := NewTripleDecoder(strings.NewReader(test.input), Turtle).DecodeAll()
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			t.Errorf("decoding %q => %v; want *ParseError", test.input, err)
+			continue
+		}
+		if perr.Line != test.line || perr.Col != test.col || perr.Err.Error() != test.msg {
+			t.Errorf("decoding %q =>\n%d:%d: %v\nwant:\n%d:%d: %s", test.input, perr.Line, perr.Col, perr.Err, test.line, test.col, test.msg)
+		}
+	}
+}
+
+func TestTTLSameDocumentReferences(t *testing.T) {
+	input := `@prefix ex: <http://example.org/ns#> .
+<> ex:p <#foo>, <?q>, <../up>, <.> .
+@base <sub/doc#ignored> .
+<> ex:p <#bar> .`
+
+	dec := NewTripleDecoder(strings.NewReader(input), Turtle)
+	dec.SetOption(Base, IRI{str: "http://example.org/dir/doc#frag"})
+	ts, err := dec.DecodeAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][2]string{
+		{"http://example.org/dir/doc", "http://example.org/dir/doc#foo"},
+		{"http://example.org/dir/doc", "http://example.org/dir/doc?q"},
+		{"http://example.org/dir/doc", "http://example.org/up"},
+		{"http://example.org/dir/doc", "http://example.org/dir/"},
+		{"http://example.org/dir/sub/doc", "http://example.org/dir/sub/doc#bar"},
+	}
+	if len(ts) != len(want) {
+		t.Fatalf("decoding %s => %v; want %d triples", input, ts, len(want))
+	}
+	for i, tr := range ts {
+		if tr.Subj != Subject(IRI{str: want[i][0]}) || tr.Obj != Object(IRI{str: want[i][1]}) {
+			t.Errorf("decoding %s => %v; want <%s> ... <%s>", input, tr, want[i][0], want[i][1])
+		}
+	}
+}
+
+func TestTTLMultipleBase(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{
+			`@base <http://example.org/a/> .
+<x> <p> <y> .
+@base <b/> .
+<x> <p> <#y> .
+@base <http://other.org/c/d> .
+<x> <p> <../z> .`,
+			[]string{
+				"<http://example.org/a/x> <http://example.org/a/p> <http://example.org/a/y> .\n",
+				"<http://example.org/a/b/x> <http://example.org/a/b/p> <http://example.org/a/b/#y> .\n",
+				"<http://other.org/c/x> <http://other.org/c/p> <http://other.org/z> .\n",
+			},
+		},
+		{
+			`BASE <http://example.org/a/>
+<x> <p> <y> .
+BASE <b/>
+PREFIX e: <c/>
+e:x <p> <y> .
+@base <../> .
+e:x <p> <y> .`,
+			[]string{
+				"<http://example.org/a/x> <http://example.org/a/p> <http://example.org/a/y> .\n",
+				"<http://example.org/a/b/c/x> <http://example.org/a/b/p> <http://example.org/a/b/y> .\n",
+				"<http://example.org/a/b/c/x> <http://example.org/a/p> <http://example.org/a/y> .\n",
+			},
+		},
+	}
+	for _, test := range tests {
+		ts := mustParseTriples(t, test.input)
+		if len(ts) != len(test.want) {
+			t.Fatalf("decoding %s => %v; want %d triples", test.input, ts, len(test.want))
+		}
+		for i, tr := range ts {
+			if got := tr.Serialize(NTriples); got != test.want[i] {
+				t.Errorf("decoding %s =>\n%s\nwant:\n%s", test.input, got, test.want[i])
+			}
+		}
+	}
+}
+
+func TestTTLPrefixMidDocument(t *testing.T) {
+	input := `@prefix ex: <http://example.org/> .
+ex:a ex:p ex:b .
+@prefix foaf: <http://xmlns.com/foaf/0.1/> .
+ex:a foaf:name "A" .
+@prefix ex: <http://example.com/other/> .
+ex:a foaf:knows ex:b .
+PREFIX ex: <http://example.net/>
+ex:c ex:d ex:e .`
+
+	want := []string{
+		"<http://example.org/a> <http://example.org/p> <http://example.org/b> .\n",
+		"<http://example.org/a> <http://xmlns.com/foaf/0.1/name> \"A\" .\n",
+		"<http://example.com/other/a> <http://xmlns.com/foaf/0.1/knows> <http://example.com/other/b> .\n",
+		"<http://example.net/c> <http://example.net/d> <http://example.net/e> .\n",
+	}
+
+	// Decode one triple at a time, to make sure the directives are
+	// handled in streaming mode, not just by DecodeAll.
+	dec := NewTripleDecoder(bytes.NewBufferString(input), Turtle)
+	for i, w := range want {
+		tr, err := dec.Decode()
+		if err != nil {
+			t.Fatalf("Decode() #%d failed: %v", i, err)
+		}
+		if got := tr.Serialize(NTriples); got != w {
+			t.Errorf("Decode() #%d => %q; want %q", i, got, w)
+		}
+	}
+	if _, err := dec.Decode(); err != io.EOF {
+		t.Errorf("Decode() at end => %v; want io.EOF", err)
+	}
+
+	// A prefix cannot be used before it is declared.
+	_, err := NewTripleDecoder(bytes.NewBufferString("ex:a ex:p ex:b .\n@prefix ex: <http://example.org/> ."), Turtle).DecodeAll()
+	if err == nil {
+		t.Errorf("using a prefix before its declaration => <no error>; want error")
+	}
+}
+
+func TestTurtleStar(t *testing.T) {
+	input := `@prefix : <http://example.org/> .
+<< :alice :knows :bob >> :since 2001 .
+:alice :name "Alice" {| :source :wiki ; :confidence 0.9 |} , "Al" .
+:bob :says << :alice :age << :x :y "z"@en >> >> .
+:a :b :c {| :d :e {| :f :g |} |} .`
+
+	wantNT := `<< << <http://example.org/a> <http://example.org/b> <http://example.org/c> >> <http://example.org/d> <http://example.org/e> >> <http://example.org/f> <http://example.org/g> .
+<< <http://example.org/a> <http://example.org/b> <http://example.org/c> >> <http://example.org/d> <http://example.org/e> .
+<< <http://example.org/alice> <http://example.org/knows> <http://example.org/bob> >> <http://example.org/since> "2001"^^<http://www.w3.org/2001/XMLSchema#integer> .
+<< <http://example.org/alice> <http://example.org/name> "Alice" >> <http://example.org/confidence> "0.9"^^<http://www.w3.org/2001/XMLSchema#decimal> .
+<< <http://example.org/alice> <http://example.org/name> "Alice" >> <http://example.org/source> <http://example.org/wiki> .
+<http://example.org/a> <http://example.org/b> <http://example.org/c> .
+<http://example.org/alice> <http://example.org/name> "Al" .
+<http://example.org/alice> <http://example.org/name> "Alice" .
+<http://example.org/bob> <http://example.org/says> << <http://example.org/alice> <http://example.org/age> << <http://example.org/x> <http://example.org/y> "z"@en >> >> .
+`
+
+	wantTTL := `@prefix ex:	<http://example.org/> .
+<< ex:alice ex:knows ex:bob >>	ex:since	2001 .
+ex:a	ex:b	ex:c {| ex:d ex:e {| ex:f ex:g |} |} .
+ex:alice	ex:name	"Alice" {| ex:confidence 0.9 ; ex:source ex:wiki |} ,
+			"Al" .
+ex:bob	ex:says	<< ex:alice ex:age << ex:x ex:y "z"@en >> >> .
+`
+
+	ts := mustParseTriples(t, input)
+	g := NewGraph()
+	g.Add(ts...)
+	if got := graphString(g); got != wantNT {
+		t.Fatalf("decoding Turtle-star got:\n%s\nwant:\n%s", got, wantNT)
+	}
+
+	var buf bytes.Buffer
+	enc := NewTripleEncoder(&buf, Turtle)
+	enc.Namespaces["http://example.org/"] = "ex"
+	if err := enc.EncodeAll(ts); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != wantTTL {
+		t.Fatalf("encoding Turtle-star got:\n%s\nwant:\n%s", buf.String(), wantTTL)
+	}
+
+	g2 := NewGraph()
+	g2.Add(mustParseTriples(t, buf.String())...)
+	if got := graphString(g2); got != wantNT {
+		t.Errorf("Turtle-star roundtrip got:\n%s\nwant:\n%s", got, wantNT)
+	}
+}
+
 // ttlTestSuite is a representation of the official W3C test suite for Turtle
 // which is found at: http://www.w3.org/2013/TurtleTests/
 var ttlTestSuite = []struct {
@@ -4061,7 +4376,7 @@ x @a Item .`, "unrecognized directive", []Triple{}},
 
 	{`# No DOT
 <http://www.w3.org/2013/TurtleTests/s> <http://www.w3.org/2013/TurtleTests/p> <http://www.w3.org/2013/TurtleTests/o>`,
-		"expected triple termination, got EOF", []Triple{}},
+		"unexpected EOF: expected triple termination", []Triple{}},
 
 	//<#turtle-syntax-bad-struct-09> rdf:type rdft:TestTurtleNegativeSyntax ;
 	//   mf:name    "turtle-syntax-bad-struct-09" ;
@@ -4257,7 +4572,7 @@ x @a Item .`, "unrecognized directive", []Triple{}},
 	//   .
 
 	{`@prefix : <http://www.w3.org/2013/TurtleTests/> .
-:s :p "abc' .`, "bad literal: no closing quote: '\"'", []Triple{}},
+:s :p "abc' .`, "unterminated string literal: no closing quote: '\"'", []Triple{}},
 
 	//<#turtle-syntax-bad-string-02> rdf:type rdft:TestTurtleNegativeSyntax ;
 	//   mf:name    "turtle-syntax-bad-string-02" ;
@@ -4267,7 +4582,7 @@ x @a Item .`, "unrecognized directive", []Triple{}},
 	//   .
 
 	{`@prefix : <http://www.w3.org/2013/TurtleTests/> .
-:s :p 'abc" .`, "bad literal: no closing quote: '\\''", []Triple{}},
+:s :p 'abc" .`, "unterminated string literal: no closing quote: '\\''", []Triple{}},
 
 	//<#turtle-syntax-bad-string-03> rdf:type rdft:TestTurtleNegativeSyntax ;
 	//   mf:name    "turtle-syntax-bad-string-03" ;
@@ -4277,7 +4592,7 @@ x @a Item .`, "unrecognized directive", []Triple{}},
 	//   .
 
 	{`@prefix : <http://www.w3.org/2013/TurtleTests/> .
-:s :p '''abc' .`, "bad literal: no closing quote: '\\''", []Triple{}},
+:s :p '''abc' .`, "unterminated long string literal: no closing '''", []Triple{}},
 
 	//<#turtle-syntax-bad-string-04> rdf:type rdft:TestTurtleNegativeSyntax ;
 	//   mf:name    "turtle-syntax-bad-string-04" ;
@@ -4287,7 +4602,7 @@ x @a Item .`, "unrecognized directive", []Triple{}},
 	//   .
 
 	{`@prefix : <http://www.w3.org/2013/TurtleTests/> .
-:s :p """abc''' .`, "bad literal: no closing quote: '\"'", []Triple{}},
+:s :p """abc''' .`, "unterminated long string literal: no closing \"\"\"", []Triple{}},
 
 	//<#turtle-syntax-bad-string-05> rdf:type rdft:TestTurtleNegativeSyntax ;
 	//   mf:name    "turtle-syntax-bad-string-05" ;
@@ -4299,7 +4614,7 @@ x @a Item .`, "unrecognized directive", []Triple{}},
 	{`# Long literal with missing end
 @prefix : <http://www.w3.org/2013/TurtleTests/> .
 :s :p """abc
-def`, "bad literal: no closing quote: '\"'", []Triple{}},
+def`, "unterminated long string literal: no closing \"\"\"", []Triple{}},
 
 	//<#turtle-syntax-bad-string-06> rdf:type rdft:TestTurtleNegativeSyntax ;
 	//   mf:name    "turtle-syntax-bad-string-06" ;
@@ -4310,7 +4625,7 @@ def`, "bad literal: no closing quote: '\"'", []Triple{}},
 
 	{`# Long literal with 4"
 @prefix : <http://www.w3.org/2013/TurtleTests/> .
-:s :p """abc""""@en .`, "bad literal: no closing quote: '\"'", []Triple{}},
+:s :p """abc""""@en .`, "unterminated string literal: no closing quote: '\"'", []Triple{}},
 
 	//<#turtle-syntax-bad-string-07> rdf:type rdft:TestTurtleNegativeSyntax ;
 	//   mf:name    "turtle-syntax-bad-string-07" ;
@@ -4321,7 +4636,7 @@ def`, "bad literal: no closing quote: '\"'", []Triple{}},
 
 	{`# Long literal with 4'
 @prefix : <http://www.w3.org/2013/TurtleTests/> .
-:s :p '''abc''''@en .`, "bad literal: no closing quote: '\\''", []Triple{}},
+:s :p '''abc''''@en .`, "unterminated string literal: no closing quote: '\\''", []Triple{}},
 
 	//<#turtle-syntax-bad-num-01> rdf:type rdft:TestTurtleNegativeSyntax ;
 	//   mf:name    "turtle-syntax-bad-num-01" ;